@@ -0,0 +1,233 @@
+package browser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghetzel/go-stockutil/log"
+	"github.com/mafredri/cdp/devtool"
+)
+
+var TabCreateTimeout = 10 * time.Second
+
+type ProxyConfig struct {
+	// The proxy server to route requests through (e.g.: "http://proxy:3128", "socks5://proxy:1080").
+	Server string
+
+	// A list of hosts that should bypass the proxy.
+	BypassList []string
+
+	// The username to provide if the proxy requests authentication.
+	Username string
+
+	// The password to provide if the proxy requests authentication.
+	Password string
+}
+
+type NewTabOptions struct {
+	URL    string
+	Width  int
+	Height int
+
+	// Create the tab in a new, isolated browser context (separate cookies, cache, and storage).
+	// This is implied if a proxy is specified.
+	Isolated bool
+
+	// Route all requests made by the new tab through this proxy.
+	Proxy *ProxyConfig
+}
+
+// Open a new tab, optionally in a separate browser context that routes its requests through
+// its own proxy server.
+func (self *Browser) NewTab(options *NewTabOptions) (*Tab, error) {
+	if options == nil {
+		options = &NewTabOptions{}
+	}
+
+	var contextId string
+	var proxy = options.Proxy
+
+	if proxy != nil && proxy.Server == `` {
+		proxy = nil
+	}
+
+	if options.Isolated || proxy != nil {
+		contextArgs := map[string]interface{}{
+			`disposeOnDetach`: false,
+		}
+
+		if proxy != nil {
+			contextArgs[`proxyServer`] = proxy.Server
+
+			if len(proxy.BypassList) > 0 {
+				contextArgs[`proxyBypassList`] = strings.Join(proxy.BypassList, `,`)
+			}
+		}
+
+		if rv, err := self.Tab().RPC(`Target`, `createBrowserContext`, contextArgs); err == nil {
+			contextId = rv.R().String(`browserContextId`)
+		} else {
+			return nil, fmt.Errorf("Failed to create browser context: %v", err)
+		}
+	}
+
+	targetArgs := map[string]interface{}{
+		`url`: options.URL,
+	}
+
+	if options.URL == `` {
+		targetArgs[`url`] = DefaultStartURL
+	}
+
+	if contextId != `` {
+		targetArgs[`browserContextId`] = contextId
+	}
+
+	if options.Width > 0 {
+		targetArgs[`width`] = options.Width
+	}
+
+	if options.Height > 0 {
+		targetArgs[`height`] = options.Height
+	}
+
+	if rv, err := self.Tab().RPC(`Target`, `createTarget`, targetArgs); err == nil {
+		targetId := rv.R().String(`targetId`)
+
+		if target, err := self.waitForTarget(targetId); err == nil {
+			if tab, err := newTabFromTarget(self, target); err == nil {
+				tab.browserContextId = contextId
+
+				if proxy != nil {
+					tab.proxylock.Lock()
+					tab.proxyServer = proxy.Server
+					tab.proxyBypassList = proxy.BypassList
+					tab.proxylock.Unlock()
+				}
+
+				self.tabLock.Lock()
+				self.tabs[tab.ID()] = tab
+				self.tabLock.Unlock()
+
+				if proxy != nil && (proxy.Username != `` || proxy.Password != ``) {
+					if err := tab.SetProxyCredentials(proxy.Username, proxy.Password); err != nil {
+						return tab, err
+					}
+				}
+
+				log.Debugf("[%s] Created tab %v (context: %v)", self.ID, tab.ID(), contextId)
+				return tab, nil
+			} else {
+				return nil, err
+			}
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// Make the tab with the given ID the active tab for subsequent commands.
+func (self *Browser) SwitchTab(id string) (*Tab, error) {
+	self.tabLock.Lock()
+	defer self.tabLock.Unlock()
+
+	if tab, ok := self.tabs[id]; ok {
+		self.activeTabId = id
+
+		tab.AsyncRPC(`Page`, `bringToFront`, nil)
+
+		return tab, nil
+	} else {
+		return nil, fmt.Errorf("No such tab %q", id)
+	}
+}
+
+// Close the tab with the given ID.  If the active tab is closed, another tab becomes active.
+func (self *Browser) CloseTab(id string) error {
+	self.tabLock.Lock()
+	defer self.tabLock.Unlock()
+
+	tab, ok := self.tabs[id]
+
+	if !ok {
+		return fmt.Errorf("No such tab %q", id)
+	} else if len(self.tabs) == 1 {
+		return fmt.Errorf("Cannot close the last remaining tab")
+	}
+
+	// pick a surviving tab to issue the close from (and to become active, if necessary)
+	var survivor *Tab
+
+	for otherId, other := range self.tabs {
+		if otherId != id && (survivor == nil || otherId == self.activeTabId) {
+			survivor = other
+		}
+	}
+
+	// disconnect first so that the closing tab's detach event isn't treated as the browser going away
+	tab.closing = true
+	tab.Disconnect()
+	delete(self.tabs, id)
+
+	if self.activeTabId == id {
+		self.activeTabId = survivor.ID()
+	}
+
+	if _, err := survivor.RPC(`Target`, `closeTarget`, map[string]interface{}{
+		`targetId`: id,
+	}); err != nil {
+		return err
+	}
+
+	if contextId := tab.browserContextId; contextId != `` {
+		if _, err := survivor.RPC(`Target`, `disposeBrowserContext`, map[string]interface{}{
+			`browserContextId`: contextId,
+		}); err != nil {
+			log.Warningf("[%s] Failed to dispose browser context %v: %v", self.ID, contextId, err)
+		}
+	}
+
+	return nil
+}
+
+// Return all tabs, ordered by ID.
+func (self *Browser) Tabs() []*Tab {
+	self.tabLock.Lock()
+	defer self.tabLock.Unlock()
+
+	tabs := make([]*Tab, 0)
+
+	for _, tab := range self.tabs {
+		tabs = append(tabs, tab)
+	}
+
+	sort.Slice(tabs, func(i int, j int) bool {
+		return tabs[i].ID() < tabs[j].ID()
+	})
+
+	return tabs
+}
+
+func (self *Browser) waitForTarget(id string) (*devtool.Target, error) {
+	started := time.Now()
+
+	for time.Since(started) <= TabCreateTimeout {
+		if targets, err := self.devtools.List(self.ctx()); err == nil {
+			for _, target := range targets {
+				if target.ID == id {
+					return target, nil
+				}
+			}
+		} else {
+			return nil, err
+		}
+
+		time.Sleep(rpcConnectRetryInterval)
+	}
+
+	return nil, fmt.Errorf("Timed out waiting for tab %v to become available", id)
+}
@@ -98,11 +98,14 @@ type Browser struct {
 	NoZygote                    bool                   `argonaut:"no-zygote,long"`
 	NoSandbox                   bool                   `argonaut:"no-sandbox,long"`
 	UserAgent                   string                 `argonaut:"user-agent,long"`
+	IgnoreCertificateErrors     bool                   `argonaut:"ignore-certificate-errors,long"`
+	IgnoreCertificateSPKIList   string                 `argonaut:"ignore-certificate-errors-spki-list,long"`
 	URL                         string                 `argonaut:",positional"`
 	StartWait                   time.Duration          `argonaut:"-"`
 	Environment                 map[string]interface{} `argonaut:"-"`
 	Directory                   string                 `argonaut:"-"`
 	Preferences                 *Preferences           `argonaut:"-"`
+	DownloadDirectory           string                 `argonaut:"-"`
 	ID                          string                 `argonaut:"-"`
 	RemoteAddress               string
 	cmd                         *exec.Cmd
@@ -110,6 +113,7 @@ type Browser struct {
 	devtools                    *devtool.DevTools
 	router                      *vestigo.Router
 	isTempUserDataDir           bool
+	isTempDownloadDir           bool
 	activeTabId                 string
 	tabs                        map[string]*Tab
 	tabLock                     sync.Mutex
@@ -138,6 +142,21 @@ func Start() (*Browser, error) {
 	return browser, browser.Launch()
 }
 
+// Trust the PEM-encoded certificate authorities in the given data for all pages loaded by
+// this browser.  This must be called before Launch.
+func (self *Browser) TrustCertificateAuthorities(pemData []byte) error {
+	if hashes, err := CertificateSPKIHashes(pemData); err == nil {
+		if self.IgnoreCertificateSPKIList != `` {
+			hashes = append([]string{self.IgnoreCertificateSPKIList}, hashes...)
+		}
+
+		self.IgnoreCertificateSPKIList = strings.Join(hashes, `,`)
+		return nil
+	} else {
+		return err
+	}
+}
+
 func (self *Browser) SetScope(fsenv utils.Runtime) {
 	self.Runtime = fsenv
 }
@@ -147,6 +166,15 @@ func (self *Browser) Launch() error {
 
 	var remoteAddr = self.RemoteAddress
 
+	if self.DownloadDirectory == `` {
+		if downloadDir, err := ioutil.TempDir(``, `webfriend-downloads-`); err == nil {
+			self.DownloadDirectory = downloadDir
+			self.isTempDownloadDir = true
+		} else {
+			return err
+		}
+	}
+
 	// no remote address, so we're starting our own session
 	if remoteAddr == `` {
 		if self.UserDataDirectory == `` {
@@ -329,6 +357,14 @@ func (self *Browser) stopWithError(xerr error) error {
 }
 
 func (self *Browser) cleanupUserDataDirectory() error {
+	if self.isTempDownloadDir && pathutil.DirExists(self.DownloadDirectory) {
+		log.Debugf("[%s] Cleaning up temporary download directory %s", self.ID, self.DownloadDirectory)
+
+		if err := os.RemoveAll(self.DownloadDirectory); err != nil {
+			return err
+		}
+	}
+
 	if self.isTempUserDataDir && pathutil.DirExists(self.UserDataDirectory) {
 		log.Debugf("[%s] Cleaning up temporary profile %s", self.ID, self.UserDataDirectory)
 		return os.RemoveAll(self.UserDataDirectory)
@@ -54,6 +54,7 @@ func (self *EventWaiter) Remove() {
 
 type Event struct {
 	ID        int
+	SessionID string
 	Name      string
 	Result    *maputil.Map
 	Params    *maputil.Map
@@ -87,6 +88,7 @@ func eventFromRpcResponse(resp *RpcMessage) *Event {
 
 	return &Event{
 		ID:        int(resp.ID),
+		SessionID: resp.SessionID,
 		Name:      resp.Method,
 		Result:    maputil.M(resp.Result),
 		Params:    maputil.M(resp.Params),
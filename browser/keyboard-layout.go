@@ -0,0 +1,290 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the location of a key on the keyboard (see KeyboardEvent.location)
+const (
+	KeyLocationStandard = 0
+	KeyLocationLeft     = 1
+	KeyLocationRight    = 2
+	KeyLocationNumpad   = 3
+)
+
+// the modifier bits used by the Input domain
+const (
+	ModifierAlt     = 1
+	ModifierControl = 2
+	ModifierMeta    = 4
+	ModifierShift   = 8
+)
+
+// Describes a physical key on the keyboard, and the values it produces.
+type KeyDefinition struct {
+	// The value of KeyboardEvent.key (e.g.: "a", "Enter", "ArrowDown").
+	Key string
+
+	// The value of KeyboardEvent.key when Shift is held (e.g.: "A" for "a").
+	ShiftKey string
+
+	// The physical key code (e.g.: "KeyA", "Enter", "Digit1").
+	Code string
+
+	// The Windows virtual key code (the legacy KeyboardEvent.keyCode).
+	KeyCode int
+
+	// The text inserted by the key.
+	Text string
+
+	// The text inserted by the key when Shift is held.
+	ShiftText string
+
+	// Where the key is located on the keyboard.
+	Location int
+
+	// The modifier bit toggled while this key is held (if it is a modifier key).
+	Modifier int
+
+	// Whether the key is only produced while holding Shift (e.g.: "!" or "A").
+	Shifted bool
+}
+
+var usKeyboardLayout = make(map[string]*KeyDefinition)
+var usKeyboardLayoutFolded = make(map[string]*KeyDefinition)
+
+// alternative names that are commonly used for keys
+var keyAliases = map[string]string{
+	`ctrl`:     `Control`,
+	`cmd`:      `Meta`,
+	`command`:  `Meta`,
+	`super`:    `Meta`,
+	`win`:      `Meta`,
+	`option`:   `Alt`,
+	`esc`:      `Escape`,
+	`return`:   `Enter`,
+	`del`:      `Delete`,
+	`ins`:      `Insert`,
+	`up`:       `ArrowUp`,
+	`down`:     `ArrowDown`,
+	`left`:     `ArrowLeft`,
+	`right`:    `ArrowRight`,
+	`pgup`:     `PageUp`,
+	`pgdn`:     `PageDown`,
+	`space`:    ` `,
+	`spacebar`: ` `,
+	`plus`:     `+`,
+}
+
+func init() {
+	add := func(names []string, def KeyDefinition) {
+		for _, name := range names {
+			d := def
+			usKeyboardLayout[name] = &d
+		}
+	}
+
+	// letters
+	for i := 0; i < 26; i++ {
+		lower := string(rune('a' + i))
+		upper := string(rune('A' + i))
+		def := KeyDefinition{
+			Key:       lower,
+			ShiftKey:  upper,
+			Code:      `Key` + upper,
+			KeyCode:   65 + i,
+			Text:      lower,
+			ShiftText: upper,
+		}
+
+		add([]string{lower, def.Code}, def)
+
+		def.Key = upper
+		def.Text = upper
+		def.Shifted = true
+
+		add([]string{upper}, def)
+	}
+
+	// digits and the symbols above them
+	for i, shifted := range []string{`)`, `!`, `@`, `#`, `$`, `%`, `^`, `&`, `*`, `(`} {
+		digit := fmt.Sprintf("%d", i)
+		def := KeyDefinition{
+			Key:       digit,
+			ShiftKey:  shifted,
+			Code:      `Digit` + digit,
+			KeyCode:   48 + i,
+			Text:      digit,
+			ShiftText: shifted,
+		}
+
+		add([]string{digit, def.Code}, def)
+
+		def.Key = shifted
+		def.Text = shifted
+		def.Shifted = true
+
+		add([]string{shifted}, def)
+
+		add([]string{`Numpad` + digit}, KeyDefinition{
+			Key:      digit,
+			Code:     `Numpad` + digit,
+			KeyCode:  96 + i,
+			Text:     digit,
+			Location: KeyLocationNumpad,
+		})
+	}
+
+	// punctuation
+	for _, punct := range []struct {
+		code    string
+		keyCode int
+		key     string
+		shifted string
+	}{
+		{`Semicolon`, 186, `;`, `:`},
+		{`Equal`, 187, `=`, `+`},
+		{`Comma`, 188, `,`, `<`},
+		{`Minus`, 189, `-`, `_`},
+		{`Period`, 190, `.`, `>`},
+		{`Slash`, 191, `/`, `?`},
+		{`Backquote`, 192, "`", `~`},
+		{`BracketLeft`, 219, `[`, `{`},
+		{`Backslash`, 220, `\`, `|`},
+		{`BracketRight`, 221, `]`, `}`},
+		{`Quote`, 222, `'`, `"`},
+	} {
+		def := KeyDefinition{
+			Key:       punct.key,
+			ShiftKey:  punct.shifted,
+			Code:      punct.code,
+			KeyCode:   punct.keyCode,
+			Text:      punct.key,
+			ShiftText: punct.shifted,
+		}
+
+		add([]string{punct.key, punct.code}, def)
+
+		def.Key = punct.shifted
+		def.Text = punct.shifted
+		def.Shifted = true
+
+		add([]string{punct.shifted}, def)
+	}
+
+	add([]string{` `, `Space`}, KeyDefinition{Key: ` `, Code: `Space`, KeyCode: 32, Text: ` `})
+	add([]string{`Enter`, "\r", "\n"}, KeyDefinition{Key: `Enter`, Code: `Enter`, KeyCode: 13, Text: "\r"})
+	add([]string{`Tab`, "\t"}, KeyDefinition{Key: `Tab`, Code: `Tab`, KeyCode: 9})
+	add([]string{`Backspace`}, KeyDefinition{Key: `Backspace`, Code: `Backspace`, KeyCode: 8})
+	add([]string{`Escape`}, KeyDefinition{Key: `Escape`, Code: `Escape`, KeyCode: 27})
+	add([]string{`Delete`}, KeyDefinition{Key: `Delete`, Code: `Delete`, KeyCode: 46})
+	add([]string{`Insert`}, KeyDefinition{Key: `Insert`, Code: `Insert`, KeyCode: 45})
+	add([]string{`Home`}, KeyDefinition{Key: `Home`, Code: `Home`, KeyCode: 36})
+	add([]string{`End`}, KeyDefinition{Key: `End`, Code: `End`, KeyCode: 35})
+	add([]string{`PageUp`}, KeyDefinition{Key: `PageUp`, Code: `PageUp`, KeyCode: 33})
+	add([]string{`PageDown`}, KeyDefinition{Key: `PageDown`, Code: `PageDown`, KeyCode: 34})
+	add([]string{`ArrowLeft`}, KeyDefinition{Key: `ArrowLeft`, Code: `ArrowLeft`, KeyCode: 37})
+	add([]string{`ArrowUp`}, KeyDefinition{Key: `ArrowUp`, Code: `ArrowUp`, KeyCode: 38})
+	add([]string{`ArrowRight`}, KeyDefinition{Key: `ArrowRight`, Code: `ArrowRight`, KeyCode: 39})
+	add([]string{`ArrowDown`}, KeyDefinition{Key: `ArrowDown`, Code: `ArrowDown`, KeyCode: 40})
+	add([]string{`CapsLock`}, KeyDefinition{Key: `CapsLock`, Code: `CapsLock`, KeyCode: 20})
+	add([]string{`Pause`}, KeyDefinition{Key: `Pause`, Code: `Pause`, KeyCode: 19})
+	add([]string{`PrintScreen`}, KeyDefinition{Key: `PrintScreen`, Code: `PrintScreen`, KeyCode: 44})
+	add([]string{`ScrollLock`}, KeyDefinition{Key: `ScrollLock`, Code: `ScrollLock`, KeyCode: 145})
+	add([]string{`NumLock`}, KeyDefinition{Key: `NumLock`, Code: `NumLock`, KeyCode: 144, Location: KeyLocationNumpad})
+	add([]string{`ContextMenu`}, KeyDefinition{Key: `ContextMenu`, Code: `ContextMenu`, KeyCode: 93})
+
+	// function keys
+	for i := 1; i <= 24; i++ {
+		name := fmt.Sprintf("F%d", i)
+		add([]string{name}, KeyDefinition{Key: name, Code: name, KeyCode: 111 + i})
+	}
+
+	// the numeric keypad
+	add([]string{`NumpadAdd`}, KeyDefinition{Key: `+`, Code: `NumpadAdd`, KeyCode: 107, Text: `+`, Location: KeyLocationNumpad})
+	add([]string{`NumpadSubtract`}, KeyDefinition{Key: `-`, Code: `NumpadSubtract`, KeyCode: 109, Text: `-`, Location: KeyLocationNumpad})
+	add([]string{`NumpadMultiply`}, KeyDefinition{Key: `*`, Code: `NumpadMultiply`, KeyCode: 106, Text: `*`, Location: KeyLocationNumpad})
+	add([]string{`NumpadDivide`}, KeyDefinition{Key: `/`, Code: `NumpadDivide`, KeyCode: 111, Text: `/`, Location: KeyLocationNumpad})
+	add([]string{`NumpadDecimal`}, KeyDefinition{Key: `.`, Code: `NumpadDecimal`, KeyCode: 110, Text: `.`, Location: KeyLocationNumpad})
+	add([]string{`NumpadEnter`}, KeyDefinition{Key: `Enter`, Code: `NumpadEnter`, KeyCode: 13, Text: "\r", Location: KeyLocationNumpad})
+
+	// modifiers (the unqualified names refer to the left-hand keys)
+	for _, mod := range []struct {
+		key      string
+		keyCode  int
+		modifier int
+	}{
+		{`Shift`, 16, ModifierShift},
+		{`Control`, 17, ModifierControl},
+		{`Alt`, 18, ModifierAlt},
+		{`Meta`, 91, ModifierMeta},
+	} {
+		add([]string{mod.key, mod.key + `Left`}, KeyDefinition{
+			Key:      mod.key,
+			Code:     mod.key + `Left`,
+			KeyCode:  mod.keyCode,
+			Location: KeyLocationLeft,
+			Modifier: mod.modifier,
+		})
+
+		right := KeyDefinition{
+			Key:      mod.key,
+			Code:     mod.key + `Right`,
+			KeyCode:  mod.keyCode,
+			Location: KeyLocationRight,
+			Modifier: mod.modifier,
+		}
+
+		if mod.key == `Meta` {
+			right.KeyCode = 92
+		}
+
+		add([]string{right.Code}, right)
+	}
+
+	// multi-character names can also be looked up case-insensitively
+	for name, def := range usKeyboardLayout {
+		if len(name) > 1 {
+			usKeyboardLayoutFolded[strings.ToLower(name)] = def
+		}
+	}
+
+	for alias, name := range keyAliases {
+		usKeyboardLayoutFolded[alias] = usKeyboardLayout[name]
+	}
+}
+
+// Return the definition of the key with the given name (e.g.: "Enter", "ArrowDown", "Ctrl"),
+// physical code (e.g.: "KeyA", "Digit1"), or character (e.g.: "a", "!", "\n") on a US keyboard.
+func LookupKey(name string) (*KeyDefinition, bool) {
+	if def, ok := usKeyboardLayout[name]; ok {
+		return def, true
+	} else if def, ok := usKeyboardLayoutFolded[strings.ToLower(name)]; ok && len(name) > 1 {
+		return def, true
+	}
+
+	return nil, false
+}
+
+// Split a key combination like "Control+Shift+K" into its individual key names.  A literal plus
+// key can be given as "+" (e.g.: "Control++").
+func ParseKeyChord(chord string) []string {
+	keys := make([]string, 0)
+	current := ``
+
+	for _, r := range chord {
+		if r == '+' && current != `` {
+			keys = append(keys, current)
+			current = ``
+		} else {
+			current += string(r)
+		}
+	}
+
+	if current != `` {
+		keys = append(keys, current)
+	}
+
+	return keys
+}
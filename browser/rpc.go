@@ -38,11 +38,12 @@ func (self *RpcError) Error() string {
 }
 
 type RpcMessage struct {
-	ID     int64                  `json:"id"`
-	Method string                 `json:"method"`
-	Params map[string]interface{} `json:"params,omitempty"`
-	Result map[string]interface{} `json:"result,omitempty"`
-	Error  map[string]interface{} `json:"error,omitempty"`
+	ID        int64                  `json:"id"`
+	SessionID string                 `json:"sessionId,omitempty"`
+	Method    string                 `json:"method"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+	Result    map[string]interface{} `json:"result,omitempty"`
+	Error     map[string]interface{} `json:"error,omitempty"`
 }
 
 func (self *RpcMessage) P() *maputil.Map {
@@ -128,9 +129,16 @@ func (self *RPC) Messages() <-chan *RpcMessage {
 }
 
 func (self *RPC) Call(method string, params map[string]interface{}, timeout time.Duration) (*RpcMessage, error) {
+	return self.CallInSession(``, method, params, timeout)
+}
+
+// Call a method on the target attached to the given session (see Target.attachToTarget), or on
+// the connection's own target if sessionId is empty.
+func (self *RPC) CallInSession(sessionId string, method string, params map[string]interface{}, timeout time.Duration) (*RpcMessage, error) {
 	message := &RpcMessage{
-		Method: method,
-		Params: params,
+		SessionID: sessionId,
+		Method:    method,
+		Params:    params,
 	}
 
 	if reply, err := self.Send(message, timeout); err == nil {
@@ -159,9 +167,14 @@ func (self *RPC) Call(method string, params map[string]interface{}, timeout time
 }
 
 func (self *RPC) CallAsync(method string, params map[string]interface{}) error {
+	return self.CallAsyncInSession(``, method, params)
+}
+
+func (self *RPC) CallAsyncInSession(sessionId string, method string, params map[string]interface{}) error {
 	message := &RpcMessage{
-		Method: method,
-		Params: params,
+		SessionID: sessionId,
+		Method:    method,
+		Params:    params,
 	}
 
 	_, err := self.Send(message, 0)
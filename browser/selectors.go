@@ -0,0 +1,285 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/sliceutil"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+// Returns the given string as a quoted Javascript string literal.
+func jsString(in string) string {
+	if data, err := json.Marshal(in); err == nil {
+		return string(data)
+	} else {
+		return `""`
+	}
+}
+
+// Return a Javascript expression that evaluates to an array of all elements matching the given
+// selector, searching beneath the element (or document) that the root expression evaluates to.
+func selectorToJavascript(selector dom.Selector, root string) (string, error) {
+	atype, inner, err := selector.GetAnnotation()
+
+	if err != nil {
+		return ``, err
+	}
+
+	switch atype {
+	case `css`:
+		return fmt.Sprintf("Array.from((%s).querySelectorAll(%s))", root, jsString(inner)), nil
+
+	case `xpath`:
+		return fmt.Sprintf(`(function(root){
+			var result = document.evaluate(%s, root, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+			var out = [];
+
+			for (var i = 0; i < result.snapshotLength; i++) {
+				var node = result.snapshotItem(i);
+
+				// text and attribute nodes resolve to the element that contains them
+				if (node.nodeType !== Node.ELEMENT_NODE) {
+					node = node.parentElement || node.ownerElement;
+				}
+
+				if (node && out.indexOf(node) < 0) {
+					out.push(node);
+				}
+			}
+
+			return out;
+		})(%s)`, jsString(inner), root), nil
+
+	case `text`:
+		var matcher string
+		mode, value, flags := dom.ParseTextMatch(inner)
+
+		switch mode {
+		case dom.TextExact:
+			matcher = fmt.Sprintf("function(t){ return t === %s }", jsString(value))
+		case dom.TextRegex:
+			matcher = fmt.Sprintf("function(t){ return (new RegExp(%s, %s)).test(t) }", jsString(value), jsString(flags))
+		default:
+			matcher = fmt.Sprintf("function(t){ return t.indexOf(%s) >= 0 }", jsString(value))
+		}
+
+		return fmt.Sprintf(`(function(root, match){
+			var skip = ['SCRIPT', 'STYLE', 'NOSCRIPT', 'TEMPLATE', 'HEAD'];
+			var textOf = function(el) {
+				return (el.innerText || el.textContent || '').replace(/\s+/g, ' ').trim();
+			};
+			var matches = function(el) {
+				return (skip.indexOf(el.tagName) < 0 && match(textOf(el)));
+			};
+
+			// only return the innermost elements whose text matches, not all of their ancestors
+			return Array.from(root.querySelectorAll('*')).filter(function(el) {
+				return matches(el) && !Array.from(el.children).some(matches);
+			});
+		})(%s, %s)`, root, matcher), nil
+
+	default:
+		return ``, fmt.Errorf("Unsupported annotation type %q", atype)
+	}
+}
+
+// Return the element that queries are currently scoped to, or nil if queries search the whole document.
+func (self *Tab) Root() *dom.Element {
+	return self.root
+}
+
+// Scope all subsequent element queries to the given element (or shadow root).  A nil element
+// restores queries to searching the whole document.
+func (self *Tab) SetRoot(element *dom.Element) {
+	self.root = element
+}
+
+// Return the shadow root hosted by the given element (open or closed), or nil if it doesn't host one.
+func (self *Tab) ShadowRootOf(element *dom.Element) (*dom.Element, error) {
+	if node, err := self.RPC(`DOM`, `describeNode`, map[string]interface{}{
+		`objectId`: element.ID,
+		`depth`:    1,
+		`pierce`:   true,
+	}); err == nil {
+		for _, shadowRoot := range node.R().Slice(`node.shadowRoots`) {
+			shadowM := maputil.M(shadowRoot)
+
+			if objectId, err := self.resolveNode(shadowM.Int(`backendNodeId`)); err == nil {
+				return &dom.Element{
+					ID:   objectId,
+					Name: `#shadow-root`,
+					Attributes: map[string]interface{}{
+						`mode`: shadowM.String(`shadowRootType`),
+					},
+				}, nil
+			} else {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	} else {
+		return nil, err
+	}
+}
+
+// query for elements matching the given selector beneath the given root (or the document if root is nil)
+func (self *Tab) queryWithin(selector dom.Selector, root *dom.Element) ([]*dom.Element, error) {
+	var results interface{}
+
+	atype, inner, err := selector.GetAnnotation()
+
+	if err != nil {
+		return nil, err
+	} else if atype == `shadow` {
+		return self.piercingQuery(inner, root)
+	} else if atype == `role` {
+		role, name := dom.ParseRoleMatch(inner)
+		return self.roleQuery(role, name, root)
+	}
+
+	if root == nil {
+		if query, err := selectorToJavascript(selector, `document`); err == nil {
+			results, err = self.Evaluate(`return ` + query)
+		} else {
+			return nil, err
+		}
+	} else if query, err := selectorToJavascript(selector, `this`); err == nil {
+		results, err = self.EvaluateOn(root, `return `+query)
+	} else {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([]*dom.Element, 0)
+
+	for _, result := range sliceutil.Sliceify(results) {
+		if element, ok := result.(*dom.Element); ok {
+			elements = append(elements, element)
+		}
+	}
+
+	return elements, nil
+}
+
+// Query for elements matching the given CSS selector in the document (or beneath the given root),
+// as well as inside of every shadow root (open or closed) within it.  Closed shadow roots are
+// invisible to page scripts, so this is performed using the DOM domain's pierced document tree.
+func (self *Tab) piercingQuery(selector string, root *dom.Element) ([]*dom.Element, error) {
+	doc, err := self.RPC(`DOM`, `getDocument`, map[string]interface{}{
+		`depth`:  -1,
+		`pierce`: true,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	scope := maputil.M(doc.R().Get(`root`))
+
+	// when operating inside of a frame, search beneath that frame's document
+	if root == nil && self.Frame() != nil {
+		if frameDoc, err := self.Evaluate(`return document`); err == nil {
+			if element, ok := frameDoc.(*dom.Element); ok {
+				root = element
+			}
+		} else {
+			return nil, err
+		}
+	}
+
+	if root != nil {
+		if rv, err := self.RPC(`DOM`, `requestNode`, map[string]interface{}{
+			`objectId`: root.ID,
+		}); err == nil {
+			if found := findNodeById(scope, rv.R().Int(`nodeId`)); found != nil {
+				scope = found
+			} else {
+				return nil, fmt.Errorf("Could not locate root element in document")
+			}
+		} else {
+			return nil, err
+		}
+	}
+
+	elements := make([]*dom.Element, 0)
+	seen := make(map[int64]bool)
+
+	for _, scopeNodeId := range collectQueryScopes(scope, nil) {
+		if rv, err := self.RPC(`DOM`, `querySelectorAll`, map[string]interface{}{
+			`nodeId`:   scopeNodeId,
+			`selector`: selector,
+		}); err == nil {
+			for _, nodeId := range rv.R().Slice(`nodeIds`) {
+				id := maputil.M(map[string]interface{}{`id`: nodeId}).Int(`id`)
+
+				if seen[id] {
+					continue
+				} else {
+					seen[id] = true
+				}
+
+				if node, err := self.RPC(`DOM`, `describeNode`, map[string]interface{}{
+					`nodeId`: id,
+					`depth`:  2,
+				}); err == nil {
+					if element := self.getElementFromResult(maputil.M(node.R().Get(`node`))); element != nil {
+						elements = append(elements, element)
+					}
+				} else {
+					return nil, err
+				}
+			}
+		} else {
+			return nil, err
+		}
+	}
+
+	return elements, nil
+}
+
+// return the node in the given tree with the given nodeId
+func findNodeById(node *maputil.Map, nodeId int64) *maputil.Map {
+	if node.Int(`nodeId`) == nodeId {
+		return node
+	}
+
+	for _, key := range []string{`children`, `shadowRoots`} {
+		for _, child := range node.Slice(key) {
+			if found := findNodeById(maputil.M(child), nodeId); found != nil {
+				return found
+			}
+		}
+	}
+
+	// descend into the documents of frame owner elements
+	if contentDocument := node.Get(`contentDocument`); !contentDocument.IsNil() {
+		return findNodeById(maputil.M(contentDocument), nodeId)
+	}
+
+	return nil
+}
+
+// return the nodeIds of the given node and of every shadow root beneath it
+func collectQueryScopes(node *maputil.Map, scopes []int64) []int64 {
+	if len(scopes) == 0 {
+		scopes = append(scopes, node.Int(`nodeId`))
+	}
+
+	for _, shadowRoot := range node.Slice(`shadowRoots`) {
+		shadowM := maputil.M(shadowRoot)
+		scopes = append(scopes, shadowM.Int(`nodeId`))
+		scopes = collectQueryScopes(shadowM, scopes)
+	}
+
+	for _, child := range node.Slice(`children`) {
+		scopes = collectQueryScopes(maputil.M(child), scopes)
+	}
+
+	return scopes
+}
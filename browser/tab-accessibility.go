@@ -0,0 +1,188 @@
+package browser
+
+import (
+	"fmt"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+type AXNode struct {
+	ID            string
+	Role          string
+	Name          string
+	Value         interface{}
+	Description   string
+	Ignored       bool
+	Properties    map[string]interface{}
+	Children      []*AXNode
+	BackendNodeID int64
+}
+
+func axNodeFromResult(node *maputil.Map) *AXNode {
+	axnode := &AXNode{
+		ID:            node.String(`nodeId`),
+		Role:          node.String(`role.value`),
+		Name:          node.String(`name.value`),
+		Value:         node.Get(`value.value`).Value,
+		Description:   node.String(`description.value`),
+		Ignored:       node.Bool(`ignored`),
+		Properties:    make(map[string]interface{}),
+		Children:      make([]*AXNode, 0),
+		BackendNodeID: node.Int(`backendDOMNodeId`),
+	}
+
+	for _, property := range node.Slice(`properties`) {
+		propM := maputil.M(property)
+		axnode.Properties[propM.String(`name`)] = propM.Get(`value.value`).Value
+	}
+
+	return axnode
+}
+
+func (self *Tab) enableAccessibility() error {
+	if !self.accessibilityEnabled {
+		if _, err := self.RPC(`Accessibility`, `enable`, nil); err == nil {
+			self.accessibilityEnabled = true
+		} else {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Retrieve the accessibility tree for the current page (or active frame), optionally starting
+// from the given element.
+func (self *Tab) AccessibilityTree(root *dom.Element) (*AXNode, error) {
+	var rootBackendId int64
+
+	if err := self.enableAccessibility(); err != nil {
+		return nil, err
+	}
+
+	if root != nil {
+		if node, err := self.RPC(`DOM`, `describeNode`, map[string]interface{}{
+			`objectId`: root.ID,
+		}); err == nil {
+			rootBackendId = node.R().Int(`node.backendNodeId`)
+		} else {
+			return nil, err
+		}
+	}
+
+	args := map[string]interface{}{}
+
+	if frame := self.Frame(); frame != nil {
+		args[`frameId`] = frame.ID
+	}
+
+	if rv, err := self.RPC(`Accessibility`, `getFullAXTree`, args); err == nil {
+		var top *AXNode
+		var nodes = make(map[string]*AXNode)
+		var order = make([]*AXNode, 0)
+		var childIds = make(map[string][]typeutil.Variant)
+
+		for _, result := range rv.R().Slice(`nodes`) {
+			resultM := maputil.M(result)
+			axnode := axNodeFromResult(resultM)
+
+			nodes[axnode.ID] = axnode
+			childIds[axnode.ID] = resultM.Slice(`childIds`)
+			order = append(order, axnode)
+
+			if rootBackendId > 0 {
+				if top == nil && axnode.BackendNodeID == rootBackendId {
+					top = axnode
+				}
+			} else if top == nil && resultM.String(`parentId`) == `` {
+				top = axnode
+			}
+		}
+
+		for _, axnode := range order {
+			for _, childId := range childIds[axnode.ID] {
+				if child, ok := nodes[childId.String()]; ok {
+					axnode.Children = append(axnode.Children, child)
+				}
+			}
+		}
+
+		if top == nil {
+			return nil, fmt.Errorf("Could not locate the root of the accessibility tree")
+		}
+
+		return top, nil
+	} else {
+		return nil, err
+	}
+}
+
+// query for elements beneath the given root (or the document) with the given ARIA role whose
+// accessible name matches the given text expression
+func (self *Tab) roleQuery(role string, name string, root *dom.Element) ([]*dom.Element, error) {
+	if err := self.enableAccessibility(); err != nil {
+		return nil, err
+	}
+
+	if root == nil {
+		if doc, err := self.Evaluate(`return document`); err == nil {
+			if element, ok := doc.(*dom.Element); ok {
+				root = element
+			} else {
+				return nil, fmt.Errorf("Could not retrieve the document")
+			}
+		} else {
+			return nil, err
+		}
+	}
+
+	queryArgs := map[string]interface{}{
+		`objectId`: root.ID,
+	}
+
+	if role != `` {
+		queryArgs[`role`] = role
+	}
+
+	// exact names can be matched by the browser; everything else is filtered below
+	if mode, value, _ := dom.ParseTextMatch(name); name != `` && mode == dom.TextExact {
+		queryArgs[`accessibleName`] = value
+	}
+
+	if rv, err := self.RPC(`Accessibility`, `queryAXTree`, queryArgs); err == nil {
+		elements := make([]*dom.Element, 0)
+
+		for _, result := range rv.R().Slice(`nodes`) {
+			axnode := axNodeFromResult(maputil.M(result))
+
+			if axnode.Ignored || axnode.BackendNodeID == 0 {
+				continue
+			}
+
+			if name != `` {
+				if matches, err := dom.MatchText(name, axnode.Name); err != nil {
+					return nil, err
+				} else if !matches {
+					continue
+				}
+			}
+
+			if node, err := self.RPC(`DOM`, `describeNode`, map[string]interface{}{
+				`backendNodeId`: axnode.BackendNodeID,
+				`depth`:         2,
+			}); err == nil {
+				if element := self.getElementFromResult(maputil.M(node.R().Get(`node`))); element != nil {
+					elements = append(elements, element)
+				}
+			} else {
+				return nil, err
+			}
+		}
+
+		return elements, nil
+	} else {
+		return nil, err
+	}
+}
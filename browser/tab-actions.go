@@ -0,0 +1,196 @@
+package browser
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+// called on an element with the node found by hit testing; returns an empty string if the node is
+// the element (or inside of it), or a short description of the node that is covering it
+var hitTargetFn = `function(hit) {
+	for (var node = hit; node; node = (node.parentNode || node.host)) {
+		if (node === this) {
+			return '';
+		}
+	}
+
+	if (!hit) {
+		return 'nothing';
+	}
+
+	var desc = (hit.localName || hit.nodeName);
+
+	if (hit.id) {
+		desc += '#' + hit.id;
+	}
+
+	if (hit.classList && hit.classList.length) {
+		desc += '.' + Array.prototype.slice.call(hit.classList).join('.');
+	}
+
+	return desc;
+}`
+
+// Scroll the given element into view (if it is not already visible in the viewport).
+func (self *Tab) ScrollIntoView(element *dom.Element) error {
+	_, err := self.RPC(`DOM`, `scrollIntoViewIfNeeded`, map[string]interface{}{
+		`objectId`: element.ID,
+	})
+
+	if isStaleObjectErr(err) {
+		return dom.StaleElementErr(element.ID)
+	}
+
+	return err
+}
+
+// Scroll the given element into view and verify that it can receive pointer input: it must be
+// visible, enabled, and not covered by any other element at its center.  Returns the coordinates
+// of that center point (relative to the viewport of the top-level page), or an error that satisfies
+// dom.IsNotActionableErr describing why the element cannot be interacted with.
+func (self *Tab) ClickablePoint(element *dom.Element) (float64, float64, error) {
+	if err := self.ScrollIntoView(element); err != nil {
+		return 0, 0, err
+	}
+
+	if err := self.DescribeElements([]*dom.Element{element}); err != nil {
+		return 0, 0, err
+	}
+
+	if !element.Visible {
+		return 0, 0, dom.NotActionableErr(element, `is not visible`)
+	} else if !element.Enabled {
+		return 0, 0, dom.NotActionableErr(element, `is disabled`)
+	}
+
+	x, y, err := self.elementCenter(element)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// hit test the center point to make sure nothing else would receive the click
+	if rv, err := self.RPC(`DOM`, `getNodeForLocation`, map[string]interface{}{
+		`x`:                         int(x),
+		`y`:                         int(y),
+		`includeUserAgentShadowDOM`: false,
+		`ignorePointerEventsNone`:   true,
+	}); err == nil {
+		if hitId, err := self.resolveNode(rv.R().Int(`backendNodeId`)); err == nil {
+			if result, err := self.RPC(`Runtime`, `callFunctionOn`, map[string]interface{}{
+				`objectId`:            element.ID,
+				`functionDeclaration`: hitTargetFn,
+				`arguments`: []interface{}{
+					map[string]interface{}{
+						`objectId`: hitId,
+					},
+				},
+				`returnByValue`: true,
+			}); err == nil {
+				if obscuredBy := result.R().String(`result.value`); obscuredBy != `` {
+					return 0, 0, dom.NotActionableErr(element, fmt.Sprintf("is obscured by %s", obscuredBy))
+				}
+			} else {
+				return 0, 0, err
+			}
+		} else {
+			return 0, 0, err
+		}
+	} else {
+		return 0, 0, err
+	}
+
+	// elements in out-of-process frames are positioned relative to that frame's own viewport
+	if dx, dy, err := self.frameOffset(self.Frame()); err == nil {
+		return x + dx, y + dy, nil
+	} else {
+		return 0, 0, err
+	}
+}
+
+// return the center of the first visible quad making up the given element, in top-level viewport coordinates
+func (self *Tab) elementCenter(element *dom.Element) (float64, float64, error) {
+	if rv, err := self.RPC(`DOM`, `getContentQuads`, map[string]interface{}{
+		`objectId`: element.ID,
+	}); err == nil {
+		for _, quad := range rv.R().Slice(`quads`) {
+			points := quad.Slice()
+
+			if len(points) != 8 {
+				continue
+			}
+
+			var x, y, area float64
+
+			// shoelace formula for the area of the quad
+			for i := 0; i < 4; i++ {
+				x1, y1 := points[i*2].Float(), points[i*2+1].Float()
+				x2, y2 := points[((i+1)%4)*2].Float(), points[((i+1)%4)*2+1].Float()
+
+				x += x1 / 4
+				y += y1 / 4
+				area += (x1*y2 - x2*y1) / 2
+			}
+
+			if math.Abs(area) > 1 {
+				return x, y, nil
+			}
+		}
+
+		return 0, 0, dom.NotActionableErr(element, `has no visible area`)
+	} else if isStaleObjectErr(err) {
+		return 0, 0, dom.StaleElementErr(element.ID)
+	} else {
+		return 0, 0, err
+	}
+}
+
+// Click at the given coordinates by moving the mouse there, then pressing and releasing the
+// configured button.  The Action in the given config is ignored.
+func (self *Tab) ClickAt(x float64, y float64, config *MouseActionConfig) error {
+	var click MouseActionConfig
+
+	if config != nil {
+		click = *config
+	}
+
+	if click.Button == `` {
+		click.Button = Left
+	}
+
+	if click.Count <= 0 {
+		click.Count = 1
+	}
+
+	move := click
+	move.Action = Moved
+	move.Button = ``
+	move.Count = 0
+
+	if err := self.MoveMouse(x, y, &move); err != nil {
+		return err
+	}
+
+	// a double (or triple, etc.) click is delivered as a series of clicks with an increasing count
+	for i := 1; i <= click.Count; i++ {
+		press := click
+		press.Action = Pressed
+		press.Count = i
+
+		if err := self.MoveMouse(x, y, &press); err != nil {
+			return err
+		}
+
+		release := click
+		release.Action = Released
+		release.Count = i
+
+		if err := self.MoveMouse(x, y, &release); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,441 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/sliceutil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+)
+
+// The computed styles that are inlined into each element when archiving a page as HTML.
+var ArchiveComputedStyles = []string{
+	`display`, `position`, `top`, `right`, `bottom`, `left`, `z-index`, `float`, `clear`, `box-sizing`,
+	`width`, `height`, `min-width`, `min-height`, `max-width`, `max-height`,
+	`margin-top`, `margin-right`, `margin-bottom`, `margin-left`,
+	`padding-top`, `padding-right`, `padding-bottom`, `padding-left`,
+	`border-top-width`, `border-right-width`, `border-bottom-width`, `border-left-width`,
+	`border-top-style`, `border-right-style`, `border-bottom-style`, `border-left-style`,
+	`border-top-color`, `border-right-color`, `border-bottom-color`, `border-left-color`,
+	`border-top-left-radius`, `border-top-right-radius`, `border-bottom-right-radius`, `border-bottom-left-radius`,
+	`border-collapse`, `border-spacing`,
+	`color`, `background-color`, `background-image`, `background-position`, `background-size`, `background-repeat`,
+	`font-family`, `font-size`, `font-weight`, `font-style`, `line-height`, `letter-spacing`,
+	`text-align`, `text-decoration-line`, `text-transform`, `text-overflow`, `white-space`, `vertical-align`,
+	`overflow-x`, `overflow-y`, `visibility`, `opacity`, `transform`, `box-shadow`, `list-style-type`,
+	`flex-direction`, `flex-wrap`, `flex-grow`, `flex-shrink`, `flex-basis`, `order`,
+	`justify-content`, `align-items`, `align-content`, `align-self`, `row-gap`, `column-gap`,
+	`grid-template-columns`, `grid-template-rows`, `grid-column-start`, `grid-column-end`, `grid-row-start`, `grid-row-end`,
+	`object-fit`, `table-layout`,
+}
+
+var archiveVoidElements = []string{
+	`area`, `base`, `br`, `col`, `embed`, `hr`, `img`, `input`, `link`, `meta`, `source`, `track`, `wbr`,
+}
+
+var archiveCssUrl = regexp.MustCompile(`url\("?([^")]+)"?\)`)
+
+// Capture the current page as an MHTML archive, including all of its resources.
+func (self *Tab) CaptureMHTML() ([]byte, error) {
+	if rv, err := self.RPC(`Page`, `captureSnapshot`, map[string]interface{}{
+		`format`: `mhtml`,
+	}); err == nil {
+		return []byte(rv.R().String(`data`)), nil
+	} else {
+		return nil, err
+	}
+}
+
+// Capture the serialized DOM of the page (and all of its frames), along with the layout and the
+// given computed styles of every rendered node.
+func (self *Tab) CaptureDOMSnapshot(styles []string) (map[string]interface{}, error) {
+	if rv, err := self.RPC(`DOMSnapshot`, `captureSnapshot`, map[string]interface{}{
+		`computedStyles`:                 styles,
+		`includeDOMRects`:                true,
+		`includePaintOrder`:              false,
+		`includeBlendedBackgroundColors`: false,
+	}); err == nil {
+		return rv.Result, nil
+	} else {
+		return nil, err
+	}
+}
+
+// Capture the page as a single, self-contained HTML document that renders as the page currently
+// appears.  Scripts and external stylesheets are removed, the computed styles of every element are
+// inlined, frames are embedded, and images are inlined as data URIs where possible.
+func (self *Tab) CaptureHTML() ([]byte, error) {
+	if snapshot, err := self.CaptureDOMSnapshot(ArchiveComputedStyles); err == nil {
+		archiver := &pageArchiver{
+			tab:       self,
+			snapshot:  maputil.M(snapshot),
+			strings:   sliceutil.Stringify(maputil.M(snapshot).Get(`strings`).Value),
+			resources: make(map[string]archiveResource),
+			inlined:   make(map[string]string),
+		}
+
+		if tree, err := self.RPC(`Page`, `getResourceTree`, nil); err == nil {
+			archiver.loadResources(maputil.M(tree.R().Get(`frameTree`)))
+		}
+
+		if documents := archiver.snapshot.Slice(`documents`); len(documents) > 0 {
+			return []byte(archiver.document(0)), nil
+		} else {
+			return nil, fmt.Errorf("Snapshot did not contain any documents")
+		}
+	} else {
+		return nil, err
+	}
+}
+
+type archiveResource struct {
+	frameId  string
+	mimeType string
+}
+
+type pageArchiver struct {
+	tab       *Tab
+	snapshot  *maputil.Map
+	strings   []string
+	resources map[string]archiveResource
+	inlined   map[string]string
+}
+
+func (self *pageArchiver) loadResources(tree *maputil.Map) {
+	frameId := tree.String(`frame.id`)
+
+	for _, resource := range tree.Slice(`resources`) {
+		resourceM := maputil.M(resource)
+
+		self.resources[resourceM.String(`url`)] = archiveResource{
+			frameId:  frameId,
+			mimeType: resourceM.String(`mimeType`),
+		}
+	}
+
+	for _, child := range tree.Slice(`childFrames`) {
+		self.loadResources(maputil.M(child))
+	}
+}
+
+func (self *pageArchiver) str(index interface{}) string {
+	if i := int(typeutil.Int(index)); i >= 0 && i < len(self.strings) {
+		return self.strings[i]
+	}
+
+	return ``
+}
+
+// return the given URL as a data URI if the resource it refers to was loaded by the page
+func (self *pageArchiver) inline(resourceUrl string) string {
+	if data, ok := self.inlined[resourceUrl]; ok {
+		return data
+	}
+
+	data := resourceUrl
+
+	if resource, ok := self.resources[resourceUrl]; ok {
+		if rv, err := self.tab.RPC(`Page`, `getResourceContent`, map[string]interface{}{
+			`frameId`: resource.frameId,
+			`url`:     resourceUrl,
+		}); err == nil {
+			content := rv.R().String(`content`)
+
+			if !rv.R().Bool(`base64Encoded`) {
+				content = base64.StdEncoding.EncodeToString([]byte(content))
+			}
+
+			data = fmt.Sprintf("data:%s;base64,%s", resource.mimeType, content)
+		}
+	}
+
+	self.inlined[resourceUrl] = data
+	return data
+}
+
+// serialize the document at the given index in the snapshot
+func (self *pageArchiver) document(index int) string {
+	doc := maputil.M(self.snapshot.Slice(`documents`)[index])
+	nodes := maputil.M(doc.Get(`nodes`))
+	layout := maputil.M(doc.Get(`layout`))
+	base, _ := url.Parse(self.str(doc.Get(`baseURL`).Value))
+
+	parents := nodes.Slice(`parentIndex`)
+	children := make(map[int][]int)
+
+	for i, parent := range parents {
+		p := int(parent.Int())
+		children[p] = append(children[p], i)
+	}
+
+	// map each node to the computed styles it was rendered with
+	styles := make(map[int][]string)
+
+	for i, nodeIndex := range layout.Slice(`nodeIndex`) {
+		if values := layout.Slice(`styles`); i < len(values) {
+			styles[int(nodeIndex.Int())] = sliceutil.Stringify(sliceutil.MapString(values[i].Slice(), func(_ int, v string) string {
+				return self.str(v)
+			}))
+		}
+	}
+
+	rare := func(key string) map[int]interface{} {
+		out := make(map[int]interface{})
+		values := nodes.Slice(key + `.value`)
+
+		for i, nodeIndex := range nodes.Slice(key + `.index`) {
+			if i < len(values) {
+				out[int(nodeIndex.Int())] = values[i].Value
+			} else {
+				out[int(nodeIndex.Int())] = true
+			}
+		}
+
+		return out
+	}
+
+	ctx := &archiveDocument{
+		types:           nodes.Slice(`nodeType`),
+		names:           nodes.Slice(`nodeName`),
+		values:          nodes.Slice(`nodeValue`),
+		attributes:      nodes.Slice(`attributes`),
+		children:        children,
+		styles:          styles,
+		inputValues:     rare(`inputValue`),
+		inputChecked:    rare(`inputChecked`),
+		optionSelected:  rare(`optionSelected`),
+		contentDocument: rare(`contentDocumentIndex`),
+		base:            base,
+	}
+
+	ctx.hidden = self.hiddenNodes(ctx, parents, nodes.Slice(`backendNodeId`))
+
+	var out bytes.Buffer
+
+	for _, root := range children[-1] {
+		self.node(ctx, root, &out)
+	}
+
+	return out.String()
+}
+
+type archiveDocument struct {
+	types           []typeutil.Variant
+	names           []typeutil.Variant
+	values          []typeutil.Variant
+	attributes      []typeutil.Variant
+	children        map[int][]int
+	styles          map[int][]string
+	inputValues     map[int]interface{}
+	inputChecked    map[int]interface{}
+	optionSelected  map[int]interface{}
+	contentDocument map[int]interface{}
+	hidden          map[int]bool
+	base            *url.URL
+}
+
+func (self *archiveDocument) resolve(ref string) string {
+	if self.base != nil && !strings.HasPrefix(ref, `data:`) && !strings.HasPrefix(ref, `#`) {
+		if u, err := self.base.Parse(ref); err == nil {
+			return u.String()
+		}
+	}
+
+	return ref
+}
+
+// Elements that weren't laid out usually weren't rendered (display: none), but some are rendered
+// without a layout box of their own (e.g.: the options of a <select>, or elements with "display:
+// contents"), so ask the browser which ones are really hidden.  Descendants of hidden elements
+// needn't be checked, since they're hidden along with them.
+func (self *pageArchiver) hiddenNodes(ctx *archiveDocument, parents []typeutil.Variant, backendNodeIds []typeutil.Variant) map[int]bool {
+	hidden := make(map[int]bool)
+	skipped := make(map[int]bool)
+
+	defer self.tab.releaseObjectGroup(`archive`)
+
+	// nodes always come after their parents, so parents are decided before their children
+	for i, parent := range parents {
+		p := int(parent.Int())
+
+		if skipped[p] || hidden[p] {
+			skipped[i] = true
+			continue
+		} else if ctx.types[i].Int() != 1 || i >= len(backendNodeIds) {
+			continue
+		} else if _, ok := ctx.styles[i]; ok {
+			continue
+		}
+
+		switch strings.ToLower(self.str(ctx.names[i].Value)) {
+		case `html`:
+			continue
+		case `head`:
+			skipped[i] = true
+			continue
+		}
+
+		if node, err := self.tab.RPC(`DOM`, `resolveNode`, map[string]interface{}{
+			`backendNodeId`: backendNodeIds[i].Int(),
+			`objectGroup`:   `archive`,
+		}); err == nil {
+			if rv, err := self.tab.RPC(`Runtime`, `callFunctionOn`, map[string]interface{}{
+				`objectId`:            node.R().String(`object.objectId`),
+				`functionDeclaration`: `function() { return window.getComputedStyle(this).display }`,
+				`returnByValue`:       true,
+			}); err == nil {
+				hidden[i] = (rv.R().String(`result.value`) == `none`)
+			}
+		}
+	}
+
+	return hidden
+}
+
+func (self *pageArchiver) node(ctx *archiveDocument, i int, out *bytes.Buffer) {
+	name := self.str(ctx.names[i].Value)
+
+	switch ctx.types[i].Int() {
+	case 1:
+		break
+	case 3:
+		out.WriteString(html.EscapeString(self.str(ctx.values[i].Value)))
+		return
+	case 9:
+		for _, child := range ctx.children[i] {
+			self.node(ctx, child, out)
+		}
+
+		return
+	case 10:
+		out.WriteString("<!DOCTYPE html>\n")
+		return
+	case 11:
+		// shadow roots are serialized declaratively
+		out.WriteString(`<template shadowrootmode="open">`)
+
+		for _, child := range ctx.children[i] {
+			self.node(ctx, child, out)
+		}
+
+		out.WriteString(`</template>`)
+		return
+	default:
+		return
+	}
+
+	// pseudo-elements (e.g.: ::before) become real elements so that their content is preserved
+	if strings.HasPrefix(name, `::`) {
+		name = `span`
+	} else if name == strings.ToUpper(name) {
+		name = strings.ToLower(name)
+	}
+
+	attrs := make(map[string]string)
+	order := make([]string, 0)
+
+	if i < len(ctx.attributes) {
+		for _, pair := range sliceutil.Chunks(ctx.attributes[i].Slice(), 2) {
+			if len(pair) == 2 {
+				key := self.str(pair[0])
+				attrs[key] = self.str(pair[1])
+				order = append(order, key)
+			}
+		}
+	}
+
+	switch name {
+	case `script`:
+		return
+	case `link`:
+		if rel := strings.ToLower(attrs[`rel`]); strings.Contains(rel, `stylesheet`) || strings.Contains(rel, `preload`) {
+			return
+		}
+	}
+
+	out.WriteString(`<` + name)
+
+	for _, key := range order {
+		value := attrs[key]
+
+		switch strings.ToLower(key) {
+		case `style`, `srcset`, `integrity`:
+			continue
+		case `src`:
+			value = self.inline(ctx.resolve(value))
+		case `href`, `action`, `poster`:
+			value = ctx.resolve(value)
+		default:
+			// event handlers won't do anything without the page's scripts
+			if strings.HasPrefix(strings.ToLower(key), `on`) {
+				continue
+			}
+		}
+
+		out.WriteString(fmt.Sprintf(" %s=\"%s\"", key, html.EscapeString(value)))
+	}
+
+	if value, ok := ctx.inputValues[i]; ok && name != `textarea` {
+		out.WriteString(fmt.Sprintf(" value=\"%s\"", html.EscapeString(self.str(value))))
+	}
+
+	if _, ok := ctx.inputChecked[i]; ok {
+		out.WriteString(` checked`)
+	}
+
+	if _, ok := ctx.optionSelected[i]; ok {
+		out.WriteString(` selected`)
+	}
+
+	if docIndex, ok := ctx.contentDocument[i]; ok {
+		out.WriteString(fmt.Sprintf(" srcdoc=\"%s\"", html.EscapeString(self.document(int(typeutil.Int(docIndex))))))
+	}
+
+	if values, ok := ctx.styles[i]; ok {
+		declarations := make([]string, 0)
+
+		for s, value := range values {
+			if s < len(ArchiveComputedStyles) && value != `` {
+				if ArchiveComputedStyles[s] == `background-image` {
+					value = archiveCssUrl.ReplaceAllStringFunc(value, func(match string) string {
+						ref := archiveCssUrl.FindStringSubmatch(match)[1]
+						return `url("` + self.inline(ctx.resolve(ref)) + `")`
+					})
+				}
+
+				declarations = append(declarations, ArchiveComputedStyles[s]+`: `+value)
+			}
+		}
+
+		out.WriteString(fmt.Sprintf(" style=\"%s\"", html.EscapeString(strings.Join(declarations, `; `))))
+	} else if ctx.hidden[i] {
+		out.WriteString(` style="display: none"`)
+	}
+
+	out.WriteString(`>`)
+
+	if sliceutil.ContainsString(archiveVoidElements, name) {
+		return
+	}
+
+	if value, ok := ctx.inputValues[i]; ok && name == `textarea` {
+		out.WriteString(html.EscapeString(self.str(value)))
+	} else {
+		for _, child := range ctx.children[i] {
+			// the contents of <style> elements must not be escaped
+			if name == `style` && ctx.types[child].Int() == 3 {
+				out.WriteString(self.str(ctx.values[child].Value))
+			} else {
+				self.node(ctx, child, out)
+			}
+		}
+	}
+
+	out.WriteString(`</` + name + `>`)
+}
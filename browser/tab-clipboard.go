@@ -0,0 +1,139 @@
+package browser
+
+import (
+	"fmt"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+)
+
+// clipboard operations are asynchronous, and require a user gesture
+var clipboardEvaluateOptions = &evaluateOptions{
+	awaitPromise: true,
+	userGesture:  true,
+}
+
+var writeClipboardFn = `(function(text, html) {
+	var items = {
+		'text/plain': new Blob([text], { type: 'text/plain' }),
+	};
+
+	if (html) {
+		items['text/html'] = new Blob([html], { type: 'text/html' });
+	}
+
+	return navigator.clipboard.write([new ClipboardItem(items)]).then(function() {
+		return true;
+	});
+})(%s, %s)`
+
+var readClipboardFn = `(function() {
+	return navigator.clipboard.read().then(function(items) {
+		var out = { text: '', html: '' };
+		var reads = [];
+
+		items.forEach(function(item) {
+			[['text/plain', 'text'], ['text/html', 'html']].forEach(function(pair) {
+				if (item.types.indexOf(pair[0]) >= 0) {
+					reads.push(item.getType(pair[0]).then(function(blob) {
+						return blob.text();
+					}).then(function(value) {
+						out[pair[1]] = value;
+					}));
+				}
+			});
+		});
+
+		return Promise.all(reads).then(function() {
+			return out;
+		});
+	});
+})()`
+
+// Grant the current page's origin permission to read from and write to the clipboard.
+func (self *Tab) GrantClipboardAccess() error {
+	origin, err := self.evaluateValue(`location.origin`, clipboardEvaluateOptions)
+
+	if err != nil {
+		return err
+	}
+
+	args := map[string]interface{}{
+		`origin`:      typeutil.String(origin),
+		`permissions`: []string{`clipboardReadWrite`, `clipboardSanitizedWrite`},
+	}
+
+	if contextId := self.browserContextId; contextId != `` {
+		args[`browserContextId`] = contextId
+	}
+
+	_, err = self.RPC(`Browser`, `grantPermissions`, args)
+	return err
+}
+
+// Write the given text (and optionally HTML) to the clipboard.
+func (self *Tab) WriteClipboard(text string, html string) error {
+	if err := self.GrantClipboardAccess(); err != nil {
+		return err
+	}
+
+	return self.withEmulatedFocus(func() error {
+		_, err := self.evaluateValue(fmt.Sprintf(writeClipboardFn, jsString(text), jsString(html)), clipboardEvaluateOptions)
+		return err
+	})
+}
+
+// Read the plain text and HTML contents of the clipboard.
+func (self *Tab) ReadClipboard() (string, string, error) {
+	var text, html string
+
+	if err := self.GrantClipboardAccess(); err != nil {
+		return ``, ``, err
+	}
+
+	err := self.withEmulatedFocus(func() error {
+		if rv, err := self.evaluateValue(readClipboardFn, clipboardEvaluateOptions); err == nil {
+			out := maputil.M(rv)
+			text, html = out.String(`text`), out.String(`html`)
+			return nil
+		} else {
+			return err
+		}
+	})
+
+	return text, html, err
+}
+
+// the clipboard is only available to focused pages, so make the page believe it has focus for the
+// duration of fn (and no longer, since it affects how the page behaves)
+func (self *Tab) withEmulatedFocus(fn func() error) error {
+	if _, err := self.RPC(`Emulation`, `setFocusEmulationEnabled`, map[string]interface{}{
+		`enabled`: true,
+	}); err != nil {
+		return err
+	}
+
+	defer self.RPC(`Emulation`, `setFocusEmulationEnabled`, map[string]interface{}{
+		`enabled`: false,
+	})
+
+	return fn()
+}
+
+// Paste the contents of the clipboard into the focused element, firing a real "paste" event.
+func (self *Tab) Paste() error {
+	for _, action := range []KeyboardAction{KeyRaw, KeyReleased} {
+		args := keyEventArgs(keyDefinitionFor(`v`), self.heldModifiers|ModifierControl, &KeyboardActionConfig{})
+		args[`type`] = action
+
+		if action == KeyRaw {
+			args[`commands`] = []string{`paste`}
+		}
+
+		if _, err := self.RPC(`Input`, `dispatchKeyEvent`, args); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package browser
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ghetzel/go-stockutil/log"
+)
+
+var downloadTrackingEvents = `{Browser,Page}.download{WillBegin,Progress}`
+
+type DownloadState string
+
+const (
+	DownloadInProgress DownloadState = `inProgress`
+	DownloadCompleted                = `completed`
+	DownloadCanceled                 = `canceled`
+)
+
+type Download struct {
+	ID                string
+	URL               string
+	SuggestedFilename string
+	Path              string
+	MimeType          string
+	TotalBytes        int64
+	ReceivedBytes     int64
+	State             DownloadState
+	StartedAt         time.Time
+	CompletedAt       time.Time
+	retrieved         bool
+}
+
+func (self *Download) IsFinished() bool {
+	return (self.State == DownloadCompleted || self.State == DownloadCanceled)
+}
+
+// Allow downloads initiated by the page, saving them to the given directory.
+func (self *Tab) EnableDownloads(directory string) error {
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return err
+	}
+
+	// downloads are named by their GUID so that we always know where to find the finished file
+	if _, err := self.RPC(`Browser`, `setDownloadBehavior`, map[string]interface{}{
+		`behavior`:      `allowAndName`,
+		`downloadPath`:  directory,
+		`eventsEnabled`: true,
+	}); err == nil {
+		self.downloadNamedByID = true
+	} else if _, err := self.RPC(`Page`, `setDownloadBehavior`, map[string]interface{}{
+		`behavior`:     `allow`,
+		`downloadPath`: directory,
+	}); err == nil {
+		self.downloadNamedByID = false
+	} else {
+		return err
+	}
+
+	self.downloadDirectory = directory
+	log.Debugf("[tab] Downloads will be saved to %v", directory)
+
+	return nil
+}
+
+// Return all downloads that this tab has initiated, in the order they were started.
+func (self *Tab) Downloads() []*Download {
+	downloads := make([]*Download, 0)
+
+	self.downloads.Range(func(_ interface{}, value interface{}) bool {
+		if download, ok := value.(*Download); ok {
+			downloads = append(downloads, download)
+		}
+
+		return true
+	})
+
+	sort.Slice(downloads, func(i int, j int) bool {
+		return downloads[i].StartedAt.Before(downloads[j].StartedAt)
+	})
+
+	return downloads
+}
+
+// Return the earliest finished download that has not already been retrieved by a previous call
+// to this function.
+func (self *Tab) NextFinishedDownload() (*Download, bool) {
+	self.downloadlock.Lock()
+	defer self.downloadlock.Unlock()
+
+	for _, download := range self.Downloads() {
+		if download.IsFinished() && !download.retrieved {
+			download.retrieved = true
+			return download, true
+		}
+	}
+
+	return nil, false
+}
+
+func (self *Tab) handleDownloadEvent(event *Event) {
+	self.downloadlock.Lock()
+	defer self.downloadlock.Unlock()
+
+	id := event.P().String(`guid`)
+	download := &Download{
+		ID:        id,
+		State:     DownloadInProgress,
+		StartedAt: event.Timestamp,
+	}
+
+	if value, ok := self.downloads.Load(id); ok {
+		download = value.(*Download)
+	}
+
+	switch event.Name {
+	case `Browser.downloadWillBegin`, `Page.downloadWillBegin`:
+		download.URL = event.P().String(`url`)
+		download.SuggestedFilename = event.P().String(`suggestedFilename`)
+
+		if self.downloadNamedByID {
+			download.Path = filepath.Join(self.downloadDirectory, id)
+		} else {
+			download.Path = filepath.Join(self.downloadDirectory, download.SuggestedFilename)
+		}
+
+		log.Debugf("[tab] Download[%v] started: %v", id, download.URL)
+
+	case `Browser.downloadProgress`, `Page.downloadProgress`:
+		download.TotalBytes = event.P().Int(`totalBytes`)
+		download.ReceivedBytes = event.P().Int(`receivedBytes`)
+		download.State = DownloadState(event.P().String(`state`, string(DownloadInProgress)))
+
+		if download.IsFinished() {
+			download.CompletedAt = event.Timestamp
+			download.MimeType = self.detectDownloadMimeType(download)
+
+			log.Debugf("[tab] Download[%v] %s (%d bytes)", id, download.State, download.ReceivedBytes)
+		}
+	}
+
+	self.downloads.Store(id, download)
+}
+
+func (self *Tab) detectDownloadMimeType(download *Download) string {
+	var mimeType string
+
+	// prefer whatever the server told us the content was
+	self.networkRequests.Range(func(_ interface{}, value interface{}) bool {
+		if netreq, ok := value.(*NetworkRequest); ok && netreq.Response != nil {
+			if netreq.R().String(`response.url`) == download.URL {
+				mimeType = netreq.R().String(`response.mimeType`)
+			}
+		}
+
+		return true
+	})
+
+	if mimeType == `` {
+		mimeType = mime.TypeByExtension(filepath.Ext(download.SuggestedFilename))
+	}
+
+	if mimeType == `` && download.State == DownloadCompleted {
+		if file, err := os.Open(download.Path); err == nil {
+			defer file.Close()
+
+			sniff := make([]byte, 512)
+
+			if n, err := file.Read(sniff); err == nil {
+				mimeType = http.DetectContentType(sniff[:n])
+			}
+		}
+	}
+
+	return mimeType
+}
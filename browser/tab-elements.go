@@ -0,0 +1,174 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+// these are the errors Chrome returns when a remote object's execution context has been destroyed
+var staleObjectErrors = []string{
+	`Could not find object with given id`,
+	`Cannot find context with specified id`,
+	`No node with given id found`,
+}
+
+var describeElementsFn = `function(styles) {
+	var elements = Array.prototype.slice.call(arguments, 1);
+
+	var pathOf = function(el) {
+		var path = [];
+
+		for (var node = el; node && node.nodeType === Node.ELEMENT_NODE; node = node.parentElement) {
+			var segment = node.localName;
+
+			if (node.id) {
+				path.unshift(segment + '#' + CSS.escape(node.id));
+				break;
+			}
+
+			var index = 1;
+			var same = false;
+
+			for (var sib = node.previousElementSibling; sib; sib = sib.previousElementSibling) {
+				if (sib.localName === node.localName) { index++; same = true; }
+			}
+
+			for (var sib = node.nextElementSibling; sib && !same; sib = sib.nextElementSibling) {
+				if (sib.localName === node.localName) { same = true; }
+			}
+
+			path.unshift(same ? segment + ':nth-of-type(' + index + ')' : segment);
+		}
+
+		return path.join(' > ');
+	};
+
+	return elements.map(function(el) {
+		var rect = el.getBoundingClientRect();
+		var computed = window.getComputedStyle(el);
+		var details = {
+			box: {
+				width:  rect.width,
+				height: rect.height,
+				top:    rect.top,
+				left:   rect.left,
+				right:  rect.right,
+				bottom: rect.bottom,
+			},
+			visible: (
+				rect.width > 0 && rect.height > 0 &&
+				computed.visibility !== 'hidden' &&
+				computed.display !== 'none' &&
+				parseFloat(computed.opacity) > 0
+			),
+			enabled: !(el.matches && el.matches(':disabled')),
+			path:    pathOf(el),
+			styles:  {},
+		};
+
+		if ('value' in el && typeof(el.value) !== 'function') {
+			details.value = el.value;
+		}
+
+		(styles || []).forEach(function(property) {
+			details.styles[property] = computed.getPropertyValue(property);
+		});
+
+		return details;
+	});
+}`
+
+func isStaleObjectErr(err error) bool {
+	if err != nil {
+		for _, msg := range staleObjectErrors {
+			if strings.Contains(err.Error(), msg) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Populate the geometry, visibility, state, and (optionally) the given computed styles of all of
+// the given elements.  All elements are described in a single call, so they must all belong to
+// the same document.
+func (self *Tab) DescribeElements(elements []*dom.Element, styles ...string) error {
+	if len(elements) == 0 {
+		return nil
+	}
+
+	callArgs := []interface{}{
+		map[string]interface{}{
+			`value`: styles,
+		},
+	}
+
+	for _, element := range elements {
+		callArgs = append(callArgs, map[string]interface{}{
+			`objectId`: element.ID,
+		})
+	}
+
+	if rv, err := self.RPC(`Runtime`, `callFunctionOn`, map[string]interface{}{
+		`objectId`:            elements[0].ID,
+		`functionDeclaration`: describeElementsFn,
+		`arguments`:           callArgs,
+		`returnByValue`:       true,
+	}); err == nil {
+		out := rv.R()
+
+		if exc := out.Get(`exceptionDetails`); !exc.IsZero() {
+			excM := maputil.M(exc)
+
+			return fmt.Errorf(
+				"Failed to describe elements: %v",
+				excM.String(`exception.description`, excM.String(`text`)),
+			)
+		}
+
+		for i, detail := range out.Slice(`result.value`) {
+			if i >= len(elements) {
+				break
+			}
+
+			detailM := maputil.M(detail)
+			element := elements[i]
+
+			element.Box = &dom.Dimensions{
+				Width:  int(detailM.Float(`box.width`)),
+				Height: int(detailM.Float(`box.height`)),
+				Top:    int(detailM.Float(`box.top`)),
+				Left:   int(detailM.Float(`box.left`)),
+				Right:  int(detailM.Float(`box.right`)),
+				Bottom: int(detailM.Float(`box.bottom`)),
+			}
+
+			element.Visible = detailM.Bool(`visible`)
+			element.Enabled = detailM.Bool(`enabled`)
+			element.Path = detailM.String(`path`)
+
+			if value := detailM.Get(`value`); !value.IsNil() {
+				element.Value = value.Value
+			}
+
+			if len(styles) > 0 {
+				element.Styles = make(map[string]string)
+
+				for property, value := range maputil.M(detailM.Get(`styles`)).MapNative() {
+					element.Styles[property] = typeutil.String(value)
+				}
+			}
+		}
+
+		return nil
+	} else if isStaleObjectErr(err) {
+		return dom.StaleElementErr(elements[0].ID)
+	} else {
+		return err
+	}
+}
@@ -0,0 +1,343 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+// Describes how to extract a single value from the page.
+type ExtractField struct {
+	// The elements to extract the value from.  If empty, the value is extracted from the element
+	// that the field is being evaluated against (i.e.: the document root or the enclosing element).
+	Selector dom.Selector
+
+	// If set, read this attribute instead of the element's text.
+	Attribute string
+
+	// How to interpret the extracted value; one of "text", "html", "number", or "exists".
+	Type string
+
+	// A regular expression that the value must match.  If the expression contains a capture
+	// group, the value of the first group is extracted; otherwise the whole match is.
+	Match string
+
+	// Extract a list of values from all matching elements instead of from the first one.
+	Multiple bool
+
+	// The value to use if no elements match (or if no value could be extracted).
+	Default interface{}
+
+	// If set, each matching element produces an object whose keys are extracted from beneath
+	// that element.
+	Fields map[string]*ExtractField
+}
+
+// Extract structured data from the current page (or beneath the given element) according to the
+// given schema.  The schema is evaluated in a single call, except for fields whose elements can
+// only be found by the selector engine (i.e.: @shadow and @role selectors), which are extracted
+// separately.
+func (self *Tab) Extract(schema map[string]*ExtractField, root *dom.Element) (map[string]interface{}, error) {
+	var result interface{}
+
+	if root == nil {
+		root = self.Root()
+	}
+
+	scripted := make(map[string]*ExtractField)
+	resolved := make(map[string]*ExtractField)
+
+	for key, field := range schema {
+		if field == nil {
+			continue
+		} else if field.needsSelectorEngine() {
+			resolved[key] = field
+		} else {
+			scripted[key] = field
+		}
+	}
+
+	if expr, err := extractSchemaToJavascript(scripted, `root`); err == nil {
+		// the result is serialized so that it can be retrieved in one call, rather than property-by-property
+		fn := "return JSON.stringify((function(root){ return " + expr + " })"
+
+		if root == nil {
+			result, err = self.Evaluate(fn + `(document));`)
+		} else {
+			result, err = self.EvaluateOn(root, fn+`(this));`)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	data := make(map[string]interface{})
+
+	if err := json.Unmarshal([]byte(typeutil.String(result)), &data); err != nil {
+		return nil, fmt.Errorf("Invalid extraction result: %v", err)
+	}
+
+	for key, field := range resolved {
+		if value, err := self.extractField(field, root); err == nil {
+			data[key] = value
+		} else {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+	}
+
+	return data, nil
+}
+
+// whether this field (or any of its nested fields) uses a selector that can't be evaluated by a script
+func (self *ExtractField) needsSelectorEngine() bool {
+	if atype, _, err := self.Selector.GetAnnotation(); err == nil && self.Selector != `` {
+		switch atype {
+		case `shadow`, `role`:
+			return true
+		}
+	}
+
+	for _, field := range self.Fields {
+		if field != nil && field.needsSelectorEngine() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// extract a single field beneath the given element (or the document), finding its elements with
+// the selector engine and extracting its value from them with a script
+func (self *Tab) extractField(field *ExtractField, scope *dom.Element) (interface{}, error) {
+	var elements []*dom.Element
+
+	if field.Selector != `` {
+		if found, err := self.queryWithin(field.Selector, scope); err == nil {
+			elements = found
+		} else {
+			return nil, err
+		}
+	} else if scope != nil {
+		elements = []*dom.Element{scope}
+	} else if doc, err := self.Evaluate(`return document`); err == nil {
+		if element, ok := doc.(*dom.Element); ok {
+			elements = []*dom.Element{element}
+		} else {
+			return nil, fmt.Errorf("Could not retrieve the document")
+		}
+	} else {
+		return nil, err
+	}
+
+	// nested fields are extracted from beneath each element in turn
+	if len(field.Fields) > 0 {
+		values := make([]interface{}, 0)
+
+		for _, element := range elements {
+			if value, err := self.Extract(field.Fields, element); err == nil {
+				values = append(values, value)
+			} else {
+				return nil, err
+			}
+
+			if !field.Multiple {
+				break
+			}
+		}
+
+		if len(values) == 0 {
+			if field.Default != nil || !field.Multiple {
+				return field.Default, nil
+			}
+		} else if !field.Multiple {
+			return values[0], nil
+		}
+
+		return values, nil
+	}
+
+	expr, err := extractValuesToJavascript(field, `els`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+
+	if len(elements) == 0 {
+		if result, err = self.Evaluate(`var els = []; return JSON.stringify(` + expr + `);`); err != nil {
+			return nil, err
+		}
+	} else {
+		args := make([]interface{}, len(elements))
+
+		for i, element := range elements {
+			args[i] = map[string]interface{}{
+				`objectId`: element.ID,
+			}
+		}
+
+		if rv, err := self.RPC(`Runtime`, `callFunctionOn`, map[string]interface{}{
+			`objectId`:            elements[0].ID,
+			`functionDeclaration`: `function() { var els = Array.prototype.slice.call(arguments); return JSON.stringify(` + expr + `); }`,
+			`arguments`:           args,
+			`returnByValue`:       true,
+		}); err == nil {
+			out := rv.R()
+
+			if exc := out.Get(`exceptionDetails`); !exc.IsZero() {
+				excM := maputil.M(exc)
+
+				return nil, fmt.Errorf(
+					"Evaluation error: %v",
+					excM.String(`exception.description`, excM.String(`text`)),
+				)
+			}
+
+			result = out.Get(`result.value`).Value
+		} else if isStaleObjectErr(err) {
+			return nil, dom.StaleElementErr(elements[0].ID)
+		} else {
+			return nil, err
+		}
+	}
+
+	var value interface{}
+
+	if err := json.Unmarshal([]byte(typeutil.String(result)), &value); err == nil {
+		return value, nil
+	} else {
+		return nil, fmt.Errorf("Invalid extraction result: %v", err)
+	}
+}
+
+// return a Javascript object literal that extracts all fields in the schema from the element named by scope
+func extractSchemaToJavascript(schema map[string]*ExtractField, scope string) (string, error) {
+	keys := make([]string, 0)
+	props := make([]string, 0)
+
+	for key := range schema {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if field := schema[key]; field != nil {
+			if expr, err := extractFieldToJavascript(field, scope); err == nil {
+				props = append(props, fmt.Sprintf("%s: %s", jsString(key), expr))
+			} else {
+				return ``, fmt.Errorf("%s: %v", key, err)
+			}
+		}
+	}
+
+	return `{` + strings.Join(props, ",\n") + `}`, nil
+}
+
+// return a Javascript expression that extracts the given field from the element named by scope
+func extractFieldToJavascript(field *ExtractField, scope string) (string, error) {
+	var elements string
+
+	if field.Selector != `` {
+		if query, err := selectorToJavascript(field.Selector, scope); err == nil {
+			elements = query
+		} else {
+			return ``, err
+		}
+	} else {
+		elements = `[` + scope + `]`
+	}
+
+	return extractValuesToJavascript(field, elements)
+}
+
+// return a Javascript expression that extracts the given field's value from the array of elements
+// produced by the given expression
+func extractValuesToJavascript(field *ExtractField, elements string) (string, error) {
+	var value string
+
+	defaultValue := `null`
+
+	if field.Default != nil {
+		if data, err := json.Marshal(field.Default); err == nil {
+			defaultValue = string(data)
+		} else {
+			return ``, fmt.Errorf("default: %v", err)
+		}
+	}
+
+	if len(field.Fields) > 0 {
+		if expr, err := extractSchemaToJavascript(field.Fields, `el`); err == nil {
+			value = expr
+		} else {
+			return ``, err
+		}
+	} else {
+		switch field.Type {
+		case `html`:
+			value = `el.innerHTML`
+		case `exists`:
+			return fmt.Sprintf("(%s.length > 0)", elements), nil
+		case ``, `text`, `number`:
+			if field.Attribute != `` {
+				value = fmt.Sprintf("el.getAttribute(%s)", jsString(field.Attribute))
+			} else {
+				value = `(el.innerText || el.textContent || '').replace(/\s+/g, ' ').trim()`
+			}
+		default:
+			return ``, fmt.Errorf("Unsupported type %q", field.Type)
+		}
+
+		// the pattern is used as a Javascript regular expression, so it is compiled (as "re") by the
+		// page, once per field
+		if field.Match != `` {
+			value = fmt.Sprintf(`(function(s){
+				var m = (s === null ? null : String(s).match(re));
+				return (m ? (m.length > 1 ? m[1] : m[0]) : null);
+			})(%s)`, value)
+		}
+
+		if field.Type == `number` {
+			value = fmt.Sprintf(`(function(s){
+				var n = (s === null ? NaN : parseFloat(String(s).replace(/[^0-9.eE+\-]/g, '')));
+				return (isNaN(n) ? null : n);
+			})(%s)`, value)
+		}
+	}
+
+	var expr string
+
+	if field.Multiple {
+		expr = fmt.Sprintf(`(function(els, fallback){
+			var values = els.map(function(el){ return %s }).filter(function(v){ return v !== null && v !== '' });
+			return (values.length ? values : (fallback === null ? [] : fallback));
+		})(%s, %s)`, value, elements, defaultValue)
+	} else {
+		expr = fmt.Sprintf(`(function(els, fallback){
+			var el = els[0];
+			var v = (el ? %s : null);
+			return ((v === null || v === '') ? fallback : v);
+		})(%s, %s)`, value, elements, defaultValue)
+	}
+
+	if field.Match != `` && len(field.Fields) == 0 {
+		expr = fmt.Sprintf(`(function(re){ return %s })((function(pattern){
+			try {
+				return new RegExp(pattern);
+			} catch (e) {
+				throw new Error('Invalid match pattern ' + JSON.stringify(pattern) + ': ' + e.message);
+			}
+		})(%s))`, expr, jsString(field.Match))
+	}
+
+	return expr, nil
+}
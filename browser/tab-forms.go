@@ -0,0 +1,223 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghetzel/go-stockutil/pathutil"
+	"github.com/ghetzel/go-stockutil/sliceutil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+// locates the form controls beneath "this" that are identified by the given key, trying (in order)
+// the name attribute, the ID, the text of an associated <label>, and the aria-label or placeholder
+var findFormControlsFn = `
+	var key = %s;
+	var controls = 'input,select,textarea,[contenteditable]';
+	var norm = function(s) {
+		return (s || '').replace(/\s+/g, ' ').replace(/[:*]+\s*$/, '').trim().toLowerCase();
+	};
+
+	var out = Array.prototype.slice.call(this.querySelectorAll('[name]')).filter(function(el) {
+		return (el.getAttribute('name') === key);
+	});
+
+	if (!out.length) {
+		out = Array.prototype.slice.call(this.querySelectorAll(controls)).filter(function(el) {
+			return (el.id === key);
+		});
+	}
+
+	if (!out.length) {
+		Array.prototype.slice.call(this.querySelectorAll('label')).forEach(function(label) {
+			if (norm(label.innerText || label.textContent) === norm(key)) {
+				var control = label.control || label.querySelector(controls);
+
+				if (control && out.indexOf(control) < 0) {
+					out.push(control);
+				}
+			}
+		});
+	}
+
+	if (!out.length) {
+		out = Array.prototype.slice.call(this.querySelectorAll(controls)).filter(function(el) {
+			return (
+				norm(el.getAttribute('aria-label')) === norm(key) ||
+				norm(el.getAttribute('placeholder')) === norm(key)
+			);
+		});
+	}
+
+	return out;
+`
+
+// selects the options of a <select> element whose value or label matches one of the given values,
+// and returns the values that did not match any option
+var selectOptionsFn = `
+	var values = %s;
+	var norm = function(s) {
+		return (s || '').replace(/\s+/g, ' ').trim();
+	};
+
+	var matched = values.map(function() { return false; });
+	var changed = false;
+
+	Array.prototype.slice.call(this.options).forEach(function(option) {
+		var selected = false;
+
+		values.forEach(function(value, i) {
+			if (matched[i] && !this.multiple) {
+				return;
+			}
+
+			if (option.value === value || norm(option.label || option.text) === norm(value)) {
+				if (this.multiple || !matched.some(function(m) { return m; })) {
+					selected = true;
+					matched[i] = true;
+				}
+			}
+		}, this);
+
+		if (option.selected !== selected && (selected || this.multiple)) {
+			option.selected = selected;
+			changed = true;
+		}
+	}, this);
+
+	if (changed) {
+		this.dispatchEvent(new Event('input', { bubbles: true }));
+		this.dispatchEvent(new Event('change', { bubbles: true }));
+	}
+
+	return values.filter(function(value, i) { return !matched[i]; });
+`
+
+// sets the value of an input using the native setter (so that frameworks tracking the value
+// property notice the change), then fires the events a user edit would have
+var setInputValueFn = `
+	var proto = Object.getPrototypeOf(this);
+	var descriptor = Object.getOwnPropertyDescriptor(proto, 'value');
+
+	if (descriptor && descriptor.set) {
+		descriptor.set.call(this, %s);
+	} else {
+		this.value = %s;
+	}
+
+	this.dispatchEvent(new Event('input', { bubbles: true }));
+	this.dispatchEvent(new Event('change', { bubbles: true }));
+`
+
+// Return the form controls beneath the given element that are identified by the given key.  The key
+// is matched against each control's name and ID, the text of its <label>, its aria-label, and its
+// placeholder (in that order); the first of these that matches anything is used.
+func (self *Tab) FormControls(form *dom.Element, key string) ([]*dom.Element, error) {
+	if results, err := self.EvaluateOn(form, fmt.Sprintf(findFormControlsFn, jsString(key))); err == nil {
+		elements := make([]*dom.Element, 0)
+
+		for _, result := range sliceutil.Sliceify(results) {
+			if element, ok := result.(*dom.Element); ok {
+				elements = append(elements, element)
+			}
+		}
+
+		if len(elements) > 0 {
+			if err := self.DescribeElements(elements); err != nil {
+				return nil, err
+			}
+		}
+
+		return elements, nil
+	} else {
+		return nil, err
+	}
+}
+
+// Set the files selected in the given <input type="file"> element.  Paths are expanded and made
+// absolute, and must refer to existing files.
+func (self *Tab) SetFileInputFiles(element *dom.Element, files []string) error {
+	paths := make([]string, 0)
+
+	for _, file := range files {
+		if expanded, err := pathutil.ExpandUser(file); err == nil {
+			file = expanded
+		} else {
+			return err
+		}
+
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		} else {
+			return err
+		}
+
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+
+		paths = append(paths, file)
+	}
+
+	_, err := self.RPC(`DOM`, `setFileInputFiles`, map[string]interface{}{
+		`objectId`: element.ID,
+		`files`:    paths,
+	})
+
+	return err
+}
+
+// Select the options in the given <select> element whose value or label matches any of the given
+// values.  Single-select elements select the first match.  Returns the values that did not match
+// any option.
+func (self *Tab) SelectOptions(element *dom.Element, values []string) ([]string, error) {
+	quoted := make([]string, len(values))
+
+	for i, value := range values {
+		quoted[i] = jsString(value)
+	}
+
+	if rv, err := self.EvaluateOn(element, fmt.Sprintf(
+		selectOptionsFn,
+		`[`+strings.Join(quoted, `, `)+`]`,
+	)); err == nil {
+		return sliceutil.Stringify(sliceutil.Sliceify(rv)), nil
+	} else {
+		return nil, err
+	}
+}
+
+// Set the value of the given input element, firing "input" and "change" events as if the value had
+// been entered by the user.
+func (self *Tab) SetInputValue(element *dom.Element, value interface{}) error {
+	v := jsString(typeutil.String(value))
+
+	_, err := self.EvaluateOn(element, fmt.Sprintf(setInputValueFn, v, v))
+	return err
+}
+
+// Check or uncheck the given checkbox or radio button.  The element is clicked (rather than having
+// its state set directly) so that any event handlers on the page run as they would for a user.
+func (self *Tab) SetChecked(element *dom.Element, checked bool) error {
+	_, err := self.EvaluateOn(element, fmt.Sprintf(
+		"if (this.checked !== %v) { this.click() }",
+		checked,
+	))
+
+	return err
+}
+
+// Return the text of the label associated with the given form control.
+func (self *Tab) ControlLabel(element *dom.Element) (string, error) {
+	if rv, err := self.EvaluateOn(
+		element,
+		`return (this.labels && this.labels.length ? (this.labels[0].innerText || this.labels[0].textContent) : '').trim()`,
+	); err == nil {
+		return typeutil.String(rv), nil
+	} else {
+		return ``, err
+	}
+}
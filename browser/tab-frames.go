@@ -0,0 +1,350 @@
+package browser
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ghetzel/go-stockutil/log"
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/sliceutil"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+var frameTrackingEvents = `Page.frame{Attached,Navigated,Detached}`
+var contextTrackingEvents = `Runtime.executionContext{Created,Destroyed,sCleared}`
+var targetTrackingEvents = `Target.{attachedToTarget,detachedFromTarget}`
+var FrameContextTimeout = 10 * time.Second
+
+// calls to these domains operate on a document, so they are sent to the session of the active
+// frame when that frame is hosted in another process
+var frameScopedDomains = []string{
+	`Accessibility`,
+	`CSS`,
+	`DOM`,
+	`DOMSnapshot`,
+	`Overlay`,
+	`Runtime`,
+}
+
+var frameAutoAttachArgs = map[string]interface{}{
+	`autoAttach`:             true,
+	`waitForDebuggerOnStart`: false,
+	`flatten`:                true,
+}
+
+type Frame struct {
+	ID             string
+	ParentID       string
+	Name           string
+	URL            string
+	SecurityOrigin string
+	contextId      int64
+	sessionId      string
+}
+
+// Whether this is the top-level frame of the page.
+func (self *Frame) IsMain() bool {
+	return (self.ParentID == ``)
+}
+
+// Return all frames in the current page, in document order (starting with the main frame).
+func (self *Tab) Frames() ([]*Frame, error) {
+	if rv, err := self.RPC(`Page`, `getFrameTree`, nil); err == nil {
+		frames := make([]*Frame, 0)
+		self.walkFrameTree(maputil.M(rv.R().Get(`frameTree`)), &frames)
+
+		return frames, nil
+	} else {
+		return nil, err
+	}
+}
+
+// Return the frame that commands are currently operating in, or nil if commands operate on the
+// main frame.
+func (self *Tab) Frame() *Frame {
+	if id := self.activeFrameId; id != `` {
+		if value, ok := self.frames.Load(id); ok {
+			return value.(*Frame)
+		}
+	}
+
+	return nil
+}
+
+// Make all subsequent element queries and script evaluation operate inside of the given frame.
+// A nil frame returns to operating on the main frame.  The current selector root is reset, since
+// it refers to an element in the previous frame's document.
+func (self *Tab) SwitchFrame(frame *Frame) {
+	self.SetRoot(nil)
+
+	if frame == nil || frame.IsMain() {
+		self.activeFrameId = ``
+	} else {
+		self.activeFrameId = frame.ID
+		log.Debugf("[tab] Switched to frame %v (%v)", frame.ID, frame.URL)
+	}
+}
+
+// Return the frame whose contents are hosted by the given frame owner element (e.g.: an <iframe>).
+func (self *Tab) FrameForElement(element *dom.Element) (*Frame, error) {
+	if node, err := self.RPC(`DOM`, `describeNode`, map[string]interface{}{
+		`objectId`: element.ID,
+	}); err == nil {
+		if frameId := node.R().String(`node.frameId`); frameId != `` {
+			if frames, err := self.Frames(); err == nil {
+				for _, frame := range frames {
+					if frame.ID == frameId {
+						return frame, nil
+					}
+				}
+
+				return nil, fmt.Errorf("Frame %v is not attached to the page", frameId)
+			} else {
+				return nil, err
+			}
+		} else {
+			return nil, fmt.Errorf("Element <%s> does not contain a frame", element.Name)
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// return the session that calls to the given domain should be sent to: that of the active frame if
+// it is out-of-process and the domain operates on its document, otherwise the tab's own session
+func (self *Tab) sessionFor(module string) string {
+	if frame := self.Frame(); frame != nil && sliceutil.ContainsString(frameScopedDomains, module) {
+		return frame.sessionId
+	}
+
+	return ``
+}
+
+// return the position of the given frame's viewport within the top-level viewport, if it is hosted
+// in another process (frames in the same process as the page are positioned relative to the
+// top-level viewport already)
+func (self *Tab) frameOffset(frame *Frame) (float64, float64, error) {
+	var parent *Frame
+
+	if frame == nil || frame.sessionId == `` {
+		return 0, 0, nil
+	}
+
+	// frames within an out-of-process frame share its process (and viewport) unless they're
+	// out-of-process themselves
+	for {
+		parent = nil
+
+		if value, ok := self.frames.Load(frame.ParentID); ok {
+			parent = value.(*Frame)
+		}
+
+		if parent != nil && parent.sessionId == frame.sessionId {
+			frame = parent
+		} else {
+			break
+		}
+	}
+
+	var parentSession string
+
+	if parent != nil {
+		parentSession = parent.sessionId
+	}
+
+	// the frame's viewport starts at the content box of the element that hosts it
+	owner, err := self.sessionRPC(parentSession, `DOM`, `getFrameOwner`, map[string]interface{}{
+		`frameId`: frame.ID,
+	})
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	box, err := self.sessionRPC(parentSession, `DOM`, `getBoxModel`, map[string]interface{}{
+		`backendNodeId`: owner.R().Int(`backendNodeId`),
+	})
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	content := box.R().Slice(`model.content`)
+
+	if len(content) != 8 {
+		return 0, 0, fmt.Errorf("Could not determine the position of frame %v", frame.ID)
+	}
+
+	if x, y, err := self.frameOffset(parent); err == nil {
+		return content[0].Float() + x, content[1].Float() + y, nil
+	} else {
+		return 0, 0, err
+	}
+}
+
+// return the ID of the execution context that unscoped script evaluation should run in, or 0 to
+// use the main frame's default context.
+func (self *Tab) activeContextId() (int64, error) {
+	frame := self.Frame()
+
+	if frame == nil {
+		if self.activeFrameId != `` {
+			return 0, fmt.Errorf("Frame %v has been detached from the page", self.activeFrameId)
+		}
+
+		return 0, nil
+	}
+
+	// a frame that is navigating won't have a context yet, so give it a moment to create one
+	started := time.Now()
+
+	for time.Since(started) <= FrameContextTimeout {
+		if frame.contextId > 0 {
+			return frame.contextId, nil
+		}
+
+		time.Sleep(rpcConnectRetryInterval)
+	}
+
+	return 0, fmt.Errorf("Timed out waiting for frame %v to become ready", frame.ID)
+}
+
+// recursively populate the frame list from a Page.FrameTree, updating the tracked frames as we go
+func (self *Tab) walkFrameTree(tree *maputil.Map, frames *[]*Frame) {
+	frame := self.updateFrame(maputil.M(tree.Get(`frame`)))
+	*frames = append(*frames, frame)
+
+	for _, child := range tree.Slice(`childFrames`) {
+		self.walkFrameTree(maputil.M(child), frames)
+	}
+}
+
+func (self *Tab) getOrCreateFrame(id string) *Frame {
+	value, _ := self.frames.LoadOrStore(id, &Frame{
+		ID: id,
+	})
+
+	return value.(*Frame)
+}
+
+// update a tracked frame from a Page.Frame
+func (self *Tab) updateFrame(details *maputil.Map) *Frame {
+	frame := self.getOrCreateFrame(details.String(`id`))
+	frame.ParentID = details.String(`parentId`)
+	frame.Name = details.String(`name`)
+	frame.URL = details.String(`url`) + details.String(`urlFragment`)
+	frame.SecurityOrigin = details.String(`securityOrigin`)
+
+	return frame
+}
+
+func (self *Tab) handleFrameEvent(event *Event) {
+	switch event.Name {
+	case `Page.frameAttached`:
+		frame := self.getOrCreateFrame(event.P().String(`frameId`))
+		frame.ParentID = event.P().String(`parentFrameId`)
+
+	case `Page.frameNavigated`:
+		self.updateFrame(maputil.M(event.P().Get(`frame`)))
+
+	case `Page.frameDetached`:
+		id := event.P().String(`frameId`)
+
+		// frames that are swapped into another process are detached, but they still exist
+		if event.P().String(`reason`) == `swap` {
+			return
+		}
+
+		self.frames.Delete(id)
+
+		if id == self.activeFrameId {
+			log.Warningf("[tab] Active frame %v was removed from the page", id)
+		}
+	}
+}
+
+func (self *Tab) handleExecutionContextEvent(event *Event) {
+	switch event.Name {
+	case `Runtime.executionContextCreated`:
+		context := maputil.M(event.P().Get(`context`))
+
+		// only track the context that the frame's own scripts run in (context IDs are only unique
+		// within the session they were created in)
+		if context.Bool(`auxData.isDefault`) {
+			if frameId := context.String(`auxData.frameId`); frameId != `` {
+				frame := self.getOrCreateFrame(frameId)
+				frame.sessionId = event.SessionID
+				frame.contextId = context.Int(`id`)
+			}
+		}
+
+	case `Runtime.executionContextDestroyed`:
+		id := event.P().Int(`executionContextId`)
+
+		self.frames.Range(func(_ interface{}, value interface{}) bool {
+			if frame, ok := value.(*Frame); ok && frame.sessionId == event.SessionID && frame.contextId == id {
+				frame.contextId = 0
+			}
+
+			return true
+		})
+
+	case `Runtime.executionContextsCleared`:
+		self.frames.Range(func(_ interface{}, value interface{}) bool {
+			if frame, ok := value.(*Frame); ok && frame.sessionId == event.SessionID {
+				frame.contextId = 0
+			}
+
+			return true
+		})
+	}
+}
+
+// Out-of-process iframes are separate targets, which are automatically attached to (with their
+// own flattened session on the tab's connection) as they are created.
+func (self *Tab) handleTargetEvent(event *Event) {
+	sessionId := event.P().String(`sessionId`)
+
+	switch event.Name {
+	case `Target.attachedToTarget`:
+		if event.P().String(`targetInfo.type`) != `iframe` {
+			// (this has to be done in the session the target was attached from)
+			if err := self.rpc.CallAsyncInSession(event.SessionID, `Target.detachFromTarget`, map[string]interface{}{
+				`sessionId`: sessionId,
+			}); err != nil {
+				log.Warningf("[tab] Failed to detach from target: %v", err)
+			}
+
+			return
+		}
+
+		// an iframe target's ID is the ID of the frame it hosts
+		frame := self.getOrCreateFrame(event.P().String(`targetInfo.targetId`))
+		frame.sessionId = sessionId
+		frame.contextId = 0
+
+		log.Debugf("[tab] Attached to out-of-process frame %v", frame.ID)
+
+		for _, method := range []string{`Runtime.enable`, `DOM.enable`, `Overlay.enable`, `Network.enable`} {
+			if err := self.rpc.CallAsyncInSession(sessionId, method, nil); err != nil {
+				log.Warningf("[tab] Failed to setup frame %v: %v", frame.ID, err)
+				return
+			}
+		}
+
+		// frames nested within this one may be out-of-process too
+		if err := self.rpc.CallAsyncInSession(sessionId, `Target.setAutoAttach`, frameAutoAttachArgs); err != nil {
+			log.Warningf("[tab] Failed to setup frame %v: %v", frame.ID, err)
+		}
+
+	case `Target.detachedFromTarget`:
+		self.frames.Range(func(_ interface{}, value interface{}) bool {
+			if frame, ok := value.(*Frame); ok && frame.sessionId == sessionId {
+				frame.sessionId = ``
+				frame.contextId = 0
+			}
+
+			return true
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package browser
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+)
+
+// how long to wait for the browser to report that a drag has started an HTML5 drag operation
+var DragInterceptTimeout = 250 * time.Millisecond
+
+type GestureConfig struct {
+	// The number of intermediate events to emit while moving.
+	Steps int `json:"steps" default:"10"`
+
+	// How long the gesture should take to perform.
+	Duration time.Duration `json:"duration" default:"250ms"`
+
+	// If set, mouse-driven gestures travel along a human-like path instead of a straight line (in
+	// which case the path's steps and duration are used).
+	Path *PointerPath `json:"-"`
+}
+
+func (self *GestureConfig) stepDelay() time.Duration {
+	if self.Steps > 0 {
+		return self.Duration / time.Duration(self.Steps)
+	}
+
+	return 0
+}
+
+// Enable or disable touch emulation, which is required for dispatching touch gestures.
+func (self *Tab) SetTouchEmulation(enabled bool) error {
+	if _, err := self.RPC(`Emulation`, `setTouchEmulationEnabled`, map[string]interface{}{
+		`enabled`:        enabled,
+		`maxTouchPoints`: 5,
+	}); err == nil {
+		self.touchEmulation = enabled
+		return nil
+	} else {
+		return err
+	}
+}
+
+// Return whether touch emulation is enabled.
+func (self *Tab) TouchEmulation() bool {
+	return self.touchEmulation
+}
+
+// Press the left mouse button at one point, move to another, and release it.  If the page starts
+// an HTML5 drag operation (i.e.: the element being dragged is draggable), the drag data is
+// captured and dropped on the target using the browser's native drag and drop events.
+func (self *Tab) Drag(fromX float64, fromY float64, toX float64, toY float64, config *GestureConfig) error {
+	if config == nil {
+		config = &GestureConfig{}
+	}
+
+	defaults.SetDefaults(config)
+
+	// have the browser hand HTML5 drag operations to us instead of starting a native drag
+	if _, err := self.RPC(`Input`, `setInterceptDrags`, map[string]interface{}{
+		`enabled`: true,
+	}); err != nil {
+		return err
+	}
+
+	defer self.RPC(`Input`, `setInterceptDrags`, map[string]interface{}{
+		`enabled`: false,
+	})
+
+	waiter, err := self.CreateEventWaiter(`Input.dragIntercepted`)
+
+	if err != nil {
+		return err
+	}
+
+	defer waiter.Remove()
+
+	if err := self.MoveMouse(fromX, fromY, &MouseActionConfig{
+		Action: Moved,
+		Path:   config.Path,
+	}); err != nil {
+		return err
+	}
+
+	if err := self.MoveMouse(fromX, fromY, &MouseActionConfig{
+		Action: Pressed,
+		Button: Left,
+		Count:  1,
+	}); err != nil {
+		return err
+	}
+
+	var dragData interface{}
+	var points [][2]float64
+	var stepDelay = config.stepDelay
+
+	if config.Path != nil {
+		points = config.Path.Points(fromX, fromY, toX, toY)
+		stepDelay = config.Path.StepDelay
+	} else {
+		for i := 1; i <= config.Steps; i++ {
+			x, y := lerp(fromX, fromY, toX, toY, float64(i)/float64(config.Steps))
+			points = append(points, [2]float64{x, y})
+		}
+	}
+
+	for i, point := range points {
+		if err := self.MoveMouse(point[0], point[1], &MouseActionConfig{
+			Action: Moved,
+		}); err != nil {
+			return err
+		}
+
+		// the first move away from the start point will trigger a drag (if there is going to be one)
+		if i == 0 {
+			if event, err := waiter.Wait(DragInterceptTimeout); err == nil {
+				dragData = event.Params.Get(`data`).Value
+				break
+			}
+		}
+
+		time.Sleep(stepDelay())
+	}
+
+	if dragData != nil {
+		for _, dragType := range []string{`dragEnter`, `dragOver`, `drop`} {
+			if _, err := self.RPC(`Input`, `dispatchDragEvent`, map[string]interface{}{
+				`type`:      dragType,
+				`x`:         toX,
+				`y`:         toY,
+				`data`:      dragData,
+				`modifiers`: self.heldModifiers,
+			}); err != nil {
+				return fmt.Errorf("%s: %v", dragType, err)
+			}
+		}
+	}
+
+	return self.MoveMouse(toX, toY, &MouseActionConfig{
+		Action: Released,
+		Button: Left,
+		Count:  1,
+	})
+}
+
+// Tap the screen at the given coordinates.
+func (self *Tab) Tap(x float64, y float64) error {
+	if err := self.dispatchTouch(`touchStart`, [][2]float64{{x, y}}); err != nil {
+		return err
+	}
+
+	return self.dispatchTouch(`touchEnd`, nil)
+}
+
+// Touch the screen at one point, slide to another, and lift.
+func (self *Tab) Swipe(fromX float64, fromY float64, toX float64, toY float64, config *GestureConfig) error {
+	if config == nil {
+		config = &GestureConfig{}
+	}
+
+	defaults.SetDefaults(config)
+
+	if err := self.dispatchTouch(`touchStart`, [][2]float64{{fromX, fromY}}); err != nil {
+		return err
+	}
+
+	for i := 1; i <= config.Steps; i++ {
+		time.Sleep(config.stepDelay())
+
+		x, y := lerp(fromX, fromY, toX, toY, float64(i)/float64(config.Steps))
+
+		if err := self.dispatchTouch(`touchMove`, [][2]float64{{x, y}}); err != nil {
+			return err
+		}
+	}
+
+	return self.dispatchTouch(`touchEnd`, nil)
+}
+
+// Touch the screen with two fingers on either side of the given point, and move them apart (for a
+// scale greater than 1) or together (for a scale less than 1).  The fingers start the given distance
+// apart.
+func (self *Tab) Pinch(x float64, y float64, distance float64, scale float64, config *GestureConfig) error {
+	if config == nil {
+		config = &GestureConfig{}
+	}
+
+	defaults.SetDefaults(config)
+
+	if scale <= 0 {
+		return fmt.Errorf("Pinch scale must be greater than zero")
+	}
+
+	fingers := func(spread float64) [][2]float64 {
+		return [][2]float64{
+			{x - spread/2, y},
+			{x + spread/2, y},
+		}
+	}
+
+	if err := self.dispatchTouch(`touchStart`, fingers(distance)); err != nil {
+		return err
+	}
+
+	for i := 1; i <= config.Steps; i++ {
+		time.Sleep(config.stepDelay())
+
+		spread := distance + (distance*scale-distance)*(float64(i)/float64(config.Steps))
+
+		if err := self.dispatchTouch(`touchMove`, fingers(spread)); err != nil {
+			return err
+		}
+	}
+
+	return self.dispatchTouch(`touchEnd`, nil)
+}
+
+func (self *Tab) dispatchTouch(touchType string, points [][2]float64) error {
+	if !self.touchEmulation {
+		return fmt.Errorf("Touch emulation is not enabled (see the emulate_touch option of configure)")
+	}
+
+	touchPoints := make([]map[string]interface{}, 0)
+
+	for i, point := range points {
+		touchPoints = append(touchPoints, map[string]interface{}{
+			`x`:  math.Round(point[0]),
+			`y`:  math.Round(point[1]),
+			`id`: i,
+		})
+	}
+
+	_, err := self.RPC(`Input`, `dispatchTouchEvent`, map[string]interface{}{
+		`type`:        touchType,
+		`touchPoints`: touchPoints,
+		`modifiers`:   self.heldModifiers,
+	})
+
+	return err
+}
+
+// linearly interpolate between two points
+func lerp(x1 float64, y1 float64, x2 float64, y2 float64, t float64) (float64, float64) {
+	return x1 + (x2-x1)*t, y1 + (y2-y1)*t
+}
@@ -1,6 +1,8 @@
 package browser
 
 import (
+	"time"
+
 	defaults "github.com/ghetzel/go-defaults"
 )
 
@@ -16,6 +18,20 @@ func (self Button) String() string {
 	return string(self)
 }
 
+// the value of this button in MouseEvent.buttons
+func (self Button) bit() int {
+	switch self {
+	case Left:
+		return 1
+	case Right:
+		return 2
+	case Middle:
+		return 4
+	default:
+		return 0
+	}
+}
+
 type MouseAction string
 
 const (
@@ -39,6 +55,9 @@ type MouseActionConfig struct {
 	WheelX  float64     `json:"wheelX,omitempty"`
 	WheelY  float64     `json:"wheelY,omitempty"`
 	Count   int         `json:"count,omitempty"`
+
+	// If set, movements travel along a human-like path instead of jumping straight to their destination.
+	Path *PointerPath `json:"-"`
 }
 
 type KeyboardAction string
@@ -59,7 +78,12 @@ type KeyboardActionConfig struct {
 	Control bool           `json:"control,omitempty"`
 	Meta    bool           `json:"meta,omitempty"`
 	Shift   bool           `json:"shift,omitempty"`
-	KeyCode int            `json:"keycode,omitempty"`
+
+	// Override the key code reported for the key.
+	KeyCode int `json:"keycode,omitempty"`
+
+	// Report the key as being on the numeric keypad.
+	IsKeypad bool `json:"is_keypad,omitempty"`
 }
 
 func (self *Tab) MoveMouse(x float64, y float64, config *MouseActionConfig) error {
@@ -68,6 +92,11 @@ func (self *Tab) MoveMouse(x float64, y float64, config *MouseActionConfig) erro
 	}
 
 	defaults.SetDefaults(config)
+
+	if config.Path != nil && config.Action == Moved {
+		return self.MoveMouseAlong(x, y, config.Path, config)
+	}
+
 	mods := 0
 
 	if config.Alt {
@@ -86,6 +115,13 @@ func (self *Tab) MoveMouse(x float64, y float64, config *MouseActionConfig) erro
 		mods |= 8
 	}
 
+	if config.Action != Scrolled {
+		self.mouseX, self.mouseY = x, y
+	}
+
+	// include any modifier keys that are being held down
+	mods |= self.heldModifiers
+
 	args := map[string]interface{}{
 		`type`:       config.Action.String(),
 		`x`:          x,
@@ -94,10 +130,31 @@ func (self *Tab) MoveMouse(x float64, y float64, config *MouseActionConfig) erro
 		`clickCount`: config.Count,
 	}
 
-	if v := config.Button; v != `` {
+	button := config.Button
+
+	// keep track of which buttons are held down so that subsequent moves are reported as drags
+	switch config.Action {
+	case Pressed:
+		self.mouseButtons |= config.Button.bit()
+		self.heldButton = config.Button
+	case Released:
+		self.mouseButtons &^= config.Button.bit()
+
+		if self.mouseButtons == 0 {
+			self.heldButton = ``
+		}
+	case Moved:
+		if button == `` {
+			button = self.heldButton
+		}
+	}
+
+	if v := button; v != `` {
 		args[`button`] = v
 	}
 
+	args[`buttons`] = self.mouseButtons
+
 	if config.Action == Scrolled {
 		args[`deltaX`] = config.WheelX
 		args[`deltaY`] = config.WheelY
@@ -106,41 +163,200 @@ func (self *Tab) MoveMouse(x float64, y float64, config *MouseActionConfig) erro
 	return self.AsyncRPC(`Input`, `dispatchMouseEvent`, args)
 }
 
+// Dispatch a keyboard event for the named key (see LookupKey).  Modifier keys pressed with this
+// function remain held (and apply to subsequent keyboard and mouse input) until released.
 func (self *Tab) SendKey(domKeyName string, config *KeyboardActionConfig) error {
 	if config == nil {
 		config = &KeyboardActionConfig{}
 	}
 
 	defaults.SetDefaults(config)
+
+	switch config.Action {
+	case KeyReleased:
+		return self.KeyUp(domKeyName, config)
+	default:
+		return self.KeyDown(domKeyName, config)
+	}
+}
+
+// Press the named key down, holding it until KeyUp is called.
+func (self *Tab) KeyDown(domKeyName string, config *KeyboardActionConfig) error {
+	if config == nil {
+		config = &KeyboardActionConfig{}
+	}
+
+	def := keyDefinitionFor(domKeyName)
+	self.heldModifiers |= def.Modifier
+	mods := self.heldModifiers | config.modifiers()
+
+	// characters that are only typed with Shift held are reported with it held
+	if def.Shifted {
+		mods |= ModifierShift
+	}
+
+	args := keyEventArgs(def, mods, config)
+	_, autoRepeat := self.keysDown.Load(def.Code)
+
+	self.keysDown.Store(def.Code, true)
+
+	if text, _ := args[`text`].(string); text != `` {
+		args[`type`] = KeyPressed.String()
+	} else {
+		args[`type`] = KeyRaw
+	}
+
+	args[`autoRepeat`] = autoRepeat
+
+	return self.AsyncRPC(`Input`, `dispatchKeyEvent`, args)
+}
+
+// Release the named key.
+func (self *Tab) KeyUp(domKeyName string, config *KeyboardActionConfig) error {
+	if config == nil {
+		config = &KeyboardActionConfig{}
+	}
+
+	def := keyDefinitionFor(domKeyName)
+	self.heldModifiers &^= def.Modifier
+	self.keysDown.Delete(def.Code)
+
+	args := keyEventArgs(def, self.heldModifiers|config.modifiers(), config)
+	args[`type`] = KeyReleased
+	delete(args, `text`)
+	delete(args, `unmodifiedText`)
+
+	return self.AsyncRPC(`Input`, `dispatchKeyEvent`, args)
+}
+
+// Press and release the given key combination (e.g.: "Enter", "Control+Shift+K").  The keys are
+// pressed in order, held for the given duration, and released in reverse order.
+func (self *Tab) PressKeys(chord string, holdFor time.Duration, config *KeyboardActionConfig) error {
+	keys := ParseKeyChord(chord)
+
+	for i, key := range keys {
+		if err := self.KeyDown(key, config); err != nil {
+			// release the keys we already pressed before giving up
+			for j := i - 1; j >= 0; j-- {
+				self.KeyUp(keys[j], config)
+			}
+
+			return err
+		}
+	}
+
+	if holdFor > 0 {
+		time.Sleep(holdFor)
+	}
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		if err := self.KeyUp(keys[i], config); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Insert text into the focused element as if it were entered by an input method.  This is used
+// for characters that cannot be typed on the keyboard (e.g.: emoji, accented letters).
+func (self *Tab) InsertText(text string) error {
+	return self.AsyncRPC(`Input`, `insertText`, map[string]interface{}{
+		`text`: text,
+	})
+}
+
+// Return the modifier keys currently being held down (see ModifierAlt, ModifierControl, etc.)
+func (self *Tab) HeldModifiers() int {
+	return self.heldModifiers
+}
+
+// Release all keys that are currently being held down.
+func (self *Tab) ReleaseKeys() error {
+	var merr error
+
+	self.keysDown.Range(func(code interface{}, _ interface{}) bool {
+		if err := self.KeyUp(code.(string), nil); err != nil {
+			merr = err
+			return false
+		}
+
+		return true
+	})
+
+	self.heldModifiers = 0
+	return merr
+}
+
+func (self *KeyboardActionConfig) modifiers() int {
 	mods := 0
 
-	if config.Alt {
-		mods |= 1
+	if self.Alt {
+		mods |= ModifierAlt
 	}
 
-	if config.Control {
-		mods |= 2
+	if self.Control {
+		mods |= ModifierControl
 	}
 
-	if config.Meta {
-		mods |= 4
+	if self.Meta {
+		mods |= ModifierMeta
 	}
 
-	if config.Shift {
-		mods |= 8
+	if self.Shift {
+		mods |= ModifierShift
 	}
 
-	args := map[string]interface{}{
-		`type`:      config.Action.String(),
-		`modifiers`: mods,
+	return mods
+}
+
+// return the definition of the given key, or a bare definition for keys we don't know about
+func keyDefinitionFor(domKeyName string) *KeyDefinition {
+	if def, ok := LookupKey(domKeyName); ok {
+		return def
 	}
 
-	if len(domKeyName) == 1 {
-		args[`text`] = domKeyName
+	return &KeyDefinition{
+		Key:  domKeyName,
+		Code: domKeyName,
 	}
+}
 
-	args[`nativeVirtualKeyCode`] = config.KeyCode
-	args[`windowsVirtualKeyCode`] = config.KeyCode
+func keyEventArgs(def *KeyDefinition, mods int, config *KeyboardActionConfig) map[string]interface{} {
+	key := def.Key
+	text := def.Text
 
-	return self.AsyncRPC(`Input`, `dispatchKeyEvent`, args)
+	if mods&ModifierShift != 0 {
+		if def.ShiftKey != `` {
+			key = def.ShiftKey
+		}
+
+		if def.ShiftText != `` {
+			text = def.ShiftText
+		}
+	}
+
+	keyCode := def.KeyCode
+
+	if config.KeyCode > 0 {
+		keyCode = config.KeyCode
+	}
+
+	args := map[string]interface{}{
+		`key`:                   key,
+		`code`:                  def.Code,
+		`windowsVirtualKeyCode`: keyCode,
+		`nativeVirtualKeyCode`:  keyCode,
+		`location`:              def.Location,
+		`isKeypad`:              (def.Location == KeyLocationNumpad || config.IsKeypad),
+		`modifiers`:             mods,
+	}
+
+	// keyboard shortcuts (anything other than Shift) don't insert any text
+	if text != `` && mods&^ModifierShift == 0 {
+		args[`text`] = text
+		args[`unmodifiedText`] = text
+	}
+
+	return args
 }
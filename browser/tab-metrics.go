@@ -0,0 +1,240 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+)
+
+// Installed into every document (before any of the page's own scripts run) to observe the
+// performance entries that user-centric metrics are calculated from.  Observers are created with
+// the "buffered" flag, so entries recorded before the script ran are still seen when it is
+// evaluated on a page that has already loaded.
+var webVitalsObserverScript = `(function() {
+	if (window.__webfriendVitals || typeof PerformanceObserver === 'undefined') {
+		return;
+	}
+
+	var vitals = window.__webfriendVitals = {
+		fcp: 0,
+		lcp: 0,
+		lcpElement: '',
+		cls: 0,
+		fid: 0,
+		longTasks: [],
+		interactions: {},
+		session: { value: 0, first: 0, last: 0 },
+	};
+
+	var observe = function(type, fn, options) {
+		try {
+			var observer = new PerformanceObserver(function(list) {
+				list.getEntries().forEach(fn);
+			});
+
+			observer.observe(Object.assign({ type: type, buffered: true }, options || {}));
+		} catch (e) {}
+	};
+
+	observe('paint', function(entry) {
+		if (entry.name === 'first-contentful-paint') {
+			vitals.fcp = entry.startTime;
+		}
+	});
+
+	observe('largest-contentful-paint', function(entry) {
+		vitals.lcp = entry.renderTime || entry.loadTime || entry.startTime;
+
+		if (entry.element) {
+			vitals.lcpElement = entry.element.tagName.toLowerCase() + (entry.element.id ? '#' + entry.element.id : '');
+		} else {
+			vitals.lcpElement = entry.url || '';
+		}
+	});
+
+	// layout shifts are grouped into session windows (shifts less than 1s apart, lasting at most
+	// 5s), and CLS is the largest session window
+	observe('layout-shift', function(entry) {
+		if (entry.hadRecentInput) {
+			return;
+		}
+
+		var session = vitals.session;
+
+		if (session.value && entry.startTime - session.last < 1000 && entry.startTime - session.first < 5000) {
+			session.value += entry.value;
+			session.last = entry.startTime;
+		} else {
+			session.value = entry.value;
+			session.first = session.last = entry.startTime;
+		}
+
+		vitals.cls = Math.max(vitals.cls, session.value);
+	});
+
+	observe('first-input', function(entry) {
+		vitals.fid = entry.processingStart - entry.startTime;
+	});
+
+	observe('event', function(entry) {
+		if (entry.interactionId) {
+			vitals.interactions[entry.interactionId] = Math.max(vitals.interactions[entry.interactionId] || 0, entry.duration);
+		}
+	}, { durationThreshold: 16 });
+
+	observe('longtask', function(entry) {
+		vitals.longTasks.push([entry.startTime, entry.duration]);
+	});
+})()`
+
+var webVitalsReportScript = `(function() {
+	var vitals = window.__webfriendVitals;
+	var navigation = performance.getEntriesByType('navigation')[0];
+	var out = {
+		navigation: (navigation ? navigation.toJSON() : {}),
+	};
+
+	if (!vitals) {
+		return JSON.stringify(out);
+	}
+
+	// INP is the worst interaction, ignoring one in every 50 to discount outliers
+	var durations = Object.keys(vitals.interactions).map(function(id) {
+		return vitals.interactions[id];
+	}).sort(function(a, b) {
+		return b - a;
+	});
+
+	// TBT is the time the main thread was blocked (beyond 50ms per task) after the first paint
+	var tbt = 0;
+
+	vitals.longTasks.forEach(function(task) {
+		if (task[0] >= vitals.fcp) {
+			tbt += Math.max(task[1] - 50, 0);
+		}
+	});
+
+	out.vitals = {
+		ttfb:         (navigation ? navigation.responseStart : 0),
+		fcp:          vitals.fcp,
+		lcp:          vitals.lcp,
+		lcp_element:  vitals.lcpElement,
+		cls:          vitals.cls,
+		inp:          (durations.length ? durations[Math.min(Math.floor(durations.length / 50), durations.length - 1)] : 0),
+		fid:          vitals.fid,
+		tbt:          tbt,
+		long_tasks:   vitals.longTasks.length,
+		interactions: durations.length,
+	};
+
+	return JSON.stringify(out);
+})()`
+
+// metrics are always collected from the top-level document, regardless of the active frame
+var metricsEvaluateOptions = &evaluateOptions{
+	topLevel: true,
+}
+
+// User-centric performance metrics, all times in milliseconds since the start of navigation.
+type WebVitals struct {
+	// Time to First Byte: when the first byte of the page's response was received.
+	TimeToFirstByte float64 `json:"ttfb"`
+
+	// First Contentful Paint: when any content was first rendered.
+	FirstContentfulPaint float64 `json:"fcp"`
+
+	// Largest Contentful Paint: when the largest image or block of text was rendered.
+	LargestContentfulPaint float64 `json:"lcp"`
+
+	// The element (or URL of the image) that was the largest contentful paint.
+	LargestContentfulPaintElement string `json:"lcp_element,omitempty"`
+
+	// Cumulative Layout Shift: how much content unexpectedly moved around (unitless).
+	CumulativeLayoutShift float64 `json:"cls"`
+
+	// Interaction to Next Paint: how long the page took to respond to its slowest interactions.
+	InteractionToNextPaint float64 `json:"inp"`
+
+	// First Input Delay: how long the page took to start handling the first interaction.
+	FirstInputDelay float64 `json:"fid"`
+
+	// Total Blocking Time: how long the main thread was too busy to respond to input after the first
+	// paint.
+	TotalBlockingTime float64 `json:"tbt"`
+
+	// The number of tasks that blocked the main thread for longer than 50ms.
+	LongTasks int `json:"long_tasks"`
+
+	// The number of interactions (clicks, taps, and key presses) that were observed.
+	Interactions int `json:"interactions"`
+}
+
+// Start collecting performance metrics.  Observers for user-centric metrics are installed into the
+// current document and every document loaded hereafter.
+func (self *Tab) EnableMetrics() error {
+	self.metricslock.Lock()
+	defer self.metricslock.Unlock()
+
+	if self.metricsScriptId == `` {
+		if _, err := self.RPC(`Performance`, `enable`, nil); err != nil {
+			return err
+		}
+
+		if rv, err := self.RPC(`Page`, `addScriptToEvaluateOnNewDocument`, map[string]interface{}{
+			`source`: webVitalsObserverScript,
+		}); err == nil {
+			self.metricsScriptId = rv.R().String(`identifier`)
+		} else {
+			return err
+		}
+	}
+
+	// observe the current document too, which picks up any buffered entries it already has
+	_, err := self.evaluateValue(webVitalsObserverScript, metricsEvaluateOptions)
+	return err
+}
+
+// Return the browser's runtime performance metrics (e.g.: JSHeapUsedSize, LayoutCount,
+// ScriptDuration) for the current page.
+func (self *Tab) PerformanceMetrics() (map[string]float64, error) {
+	if rv, err := self.RPC(`Performance`, `getMetrics`, nil); err == nil {
+		metrics := make(map[string]float64)
+
+		for _, metric := range rv.R().Slice(`metrics`) {
+			m := maputil.M(metric.Value)
+			metrics[m.String(`name`)] = m.Float(`value`)
+		}
+
+		return metrics, nil
+	} else {
+		return nil, err
+	}
+}
+
+// Return the Navigation Timing entry and user-centric metrics for the current page.  Metrics are
+// only available if EnableMetrics was called.
+func (self *Tab) WebVitals() (map[string]interface{}, *WebVitals, error) {
+	var report struct {
+		Navigation map[string]interface{} `json:"navigation"`
+		Vitals     *WebVitals             `json:"vitals"`
+	}
+
+	if rv, err := self.evaluateValue(webVitalsReportScript, metricsEvaluateOptions); err == nil {
+		if data, ok := rv.(string); ok {
+			if err := json.Unmarshal([]byte(data), &report); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			return nil, nil, fmt.Errorf("Unexpected metrics report %T", rv)
+		}
+	} else {
+		return nil, nil, err
+	}
+
+	if report.Vitals == nil {
+		report.Vitals = &WebVitals{}
+	}
+
+	return report.Navigation, report.Vitals, nil
+}
@@ -0,0 +1,102 @@
+package browser
+
+import (
+	"fmt"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+// the attribute used to mark all elements that should be highlighted together
+var highlightAttribute = `data-webfriend-highlight`
+
+type HighlightColor struct {
+	R int
+	G int
+	B int
+	A float64
+}
+
+func (self HighlightColor) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		`r`: self.R,
+		`g`: self.G,
+		`b`: self.B,
+		`a`: self.A,
+	}
+}
+
+// Highlight the given elements in the given color.  The browser only maintains one highlight at a
+// time, so this replaces any existing highlight.
+func (self *Tab) HighlightElements(elements []*dom.Element, color HighlightColor) error {
+	if err := self.ClearHighlights(); err != nil {
+		return err
+	}
+
+	if len(elements) == 0 {
+		return nil
+	}
+
+	// mark every element so that a single highlight can cover all of them
+	for _, element := range elements {
+		if _, err := self.EvaluateOn(element, fmt.Sprintf("this.setAttribute(%s, '')", jsString(highlightAttribute))); err != nil {
+			return err
+		}
+	}
+
+	_, err := self.RPC(`Overlay`, `highlightNode`, map[string]interface{}{
+		`objectId`: elements[0].ID,
+		`selector`: `[` + highlightAttribute + `]`,
+		`highlightConfig`: map[string]interface{}{
+			`showInfo`:     (len(elements) == 1),
+			`contentColor`: color.ToMap(),
+			`borderColor`: HighlightColor{
+				R: color.R,
+				G: color.G,
+				B: color.B,
+				A: 1,
+			}.ToMap(),
+		},
+	})
+
+	return err
+}
+
+// Remove any highlight currently drawn on the page.
+func (self *Tab) ClearHighlights() error {
+	if _, err := self.RPC(`Overlay`, `hideHighlight`, nil); err != nil {
+		return err
+	}
+
+	_, err := self.Evaluate(fmt.Sprintf(
+		"document.querySelectorAll('[%s]').forEach(function(el){ el.removeAttribute(%s) })",
+		highlightAttribute,
+		jsString(highlightAttribute),
+	))
+
+	return err
+}
+
+// Return the element at the given coordinates (relative to the viewport).
+func (self *Tab) ElementAtPoint(x float64, y float64) (*dom.Element, error) {
+	if rv, err := self.RPC(`DOM`, `getNodeForLocation`, map[string]interface{}{
+		`x`:                         int(x),
+		`y`:                         int(y),
+		`includeUserAgentShadowDOM`: false,
+	}); err == nil {
+		if node, err := self.RPC(`DOM`, `describeNode`, map[string]interface{}{
+			`backendNodeId`: rv.R().Int(`backendNodeId`),
+			`depth`:         2,
+		}); err == nil {
+			if element := self.getElementFromResult(maputil.M(node.R().Get(`node`))); element != nil {
+				return element, self.DescribeElements([]*dom.Element{element})
+			} else {
+				return nil, fmt.Errorf("No element was found at the given coordinates.")
+			}
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
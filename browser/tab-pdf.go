@@ -0,0 +1,236 @@
+package browser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/ghetzel/go-stockutil/typeutil"
+)
+
+// How much of a streamed PDF to read at a time.
+var PdfStreamChunkSize = 1048576
+
+// The dimensions (width by height, in inches) of common paper sizes.
+var PaperSizes = map[string][2]float64{
+	`letter`:  {8.5, 11},
+	`legal`:   {8.5, 14},
+	`tabloid`: {11, 17},
+	`ledger`:  {17, 11},
+	`a0`:      {33.1, 46.8},
+	`a1`:      {23.4, 33.1},
+	`a2`:      {16.54, 23.4},
+	`a3`:      {11.7, 16.54},
+	`a4`:      {8.27, 11.7},
+	`a5`:      {5.83, 8.27},
+	`a6`:      {4.13, 5.83},
+}
+
+var rxPaperLength = regexp.MustCompile(`(?i)^\s*([0-9]*\.?[0-9]+)\s*(in|cm|mm|px|pt)?\s*$`)
+
+// How many of each unit make up an inch.
+var paperUnitsPerInch = map[string]float64{
+	``:   1,
+	`in`: 1,
+	`cm`: 2.54,
+	`mm`: 25.4,
+	`px`: 96,
+	`pt`: 72,
+}
+
+// Margins around each page, in inches.  Sides that are nil use the browser's default margin.
+type PdfMargins struct {
+	Top    *float64
+	Right  *float64
+	Bottom *float64
+	Left   *float64
+}
+
+type PdfOptions struct {
+	// The width and height of the paper, in inches.
+	Width  float64
+	Height float64
+
+	// Print the page in landscape orientation.
+	Landscape bool
+
+	// The margins around each page; if nil, the browser's default margins are used.
+	Margins *PdfMargins
+
+	// Print background colors and images.
+	PrintBackground bool
+
+	// The scale to render the page at.
+	Scale float64
+
+	// Which pages to print (e.g.: "1-5, 8, 11-13"); all pages are printed if empty.
+	PageRanges string
+
+	// Show a header and footer on each page, rendered from the given HTML templates.
+	DisplayHeaderFooter bool
+	HeaderTemplate      string
+	FooterTemplate      string
+
+	// Use any size defined by the page's CSS @page rules instead of Width and Height.
+	PreferCSSPageSize bool
+
+	// Embed a document outline (generated from the page's headings) in the PDF.
+	Outline bool
+
+	// Generate a tagged (accessible) PDF.
+	Tagged bool
+
+	// Have the browser hand the PDF back in chunks rather than all at once, which avoids holding
+	// very large documents in memory several times over.
+	Stream bool
+}
+
+// Parse a paper length (e.g.: 8.5, "210mm", "1in", "2.5cm", "96px", "72pt") into inches.  Plain
+// numbers are taken to be inches.
+func ParsePaperLength(value interface{}) (float64, error) {
+	if typeutil.IsNumeric(value) {
+		return typeutil.Float(value), nil
+	} else if match := rxPaperLength.FindStringSubmatch(typeutil.String(value)); match != nil {
+		return typeutil.Float(match[1]) / paperUnitsPerInch[strings.ToLower(match[2])], nil
+	} else {
+		return 0, fmt.Errorf("Invalid length %q; expected a number followed by in, cm, mm, px, or pt", value)
+	}
+}
+
+// Render the current page as a PDF, writing it to the given writer.  Returns the number of bytes
+// written.
+func (self *Tab) PrintToPDF(options *PdfOptions, w io.Writer) (int64, error) {
+	if options == nil {
+		options = &PdfOptions{}
+	}
+
+	args := map[string]interface{}{
+		`landscape`:           options.Landscape,
+		`printBackground`:     options.PrintBackground,
+		`displayHeaderFooter`: options.DisplayHeaderFooter,
+		`preferCSSPageSize`:   options.PreferCSSPageSize,
+		`scale`:               1,
+	}
+
+	// these are only sent when wanted, since older browsers don't know about them
+	if options.Outline {
+		args[`generateDocumentOutline`] = true
+	}
+
+	if options.Tagged {
+		args[`generateTaggedPDF`] = true
+	}
+
+	if options.Scale > 0 {
+		args[`scale`] = options.Scale
+	}
+
+	if options.Width > 0 {
+		args[`paperWidth`] = options.Width
+	}
+
+	if options.Height > 0 {
+		args[`paperHeight`] = options.Height
+	}
+
+	if margins := options.Margins; margins != nil {
+		if margins.Top != nil {
+			args[`marginTop`] = *margins.Top
+		}
+
+		if margins.Right != nil {
+			args[`marginRight`] = *margins.Right
+		}
+
+		if margins.Bottom != nil {
+			args[`marginBottom`] = *margins.Bottom
+		}
+
+		if margins.Left != nil {
+			args[`marginLeft`] = *margins.Left
+		}
+	}
+
+	if options.PageRanges != `` {
+		args[`pageRanges`] = options.PageRanges
+	}
+
+	if options.DisplayHeaderFooter {
+		// an empty template would render the browser's default header/footer, so explicitly blank
+		// out whichever one wasn't given
+		args[`headerTemplate`] = options.HeaderTemplate
+		args[`footerTemplate`] = options.FooterTemplate
+
+		if options.HeaderTemplate == `` {
+			args[`headerTemplate`] = `<span></span>`
+		}
+
+		if options.FooterTemplate == `` {
+			args[`footerTemplate`] = `<span></span>`
+		}
+	}
+
+	if options.Stream {
+		args[`transferMode`] = `ReturnAsStream`
+	}
+
+	if rv, err := self.RPC(`Page`, `printToPDF`, args); err == nil {
+		out := rv.R()
+
+		if handle := out.String(`stream`); handle != `` {
+			return self.readStream(handle, w)
+		} else if data := out.String(`data`); data != `` {
+			if decoded, err := base64.StdEncoding.DecodeString(data); err == nil {
+				n, err := w.Write(decoded)
+				return int64(n), err
+			} else {
+				return 0, fmt.Errorf("decode error: %v", err)
+			}
+		} else {
+			return 0, fmt.Errorf("Empty response")
+		}
+	} else {
+		return 0, err
+	}
+}
+
+// read the given stream to the end, copying its contents to the given writer
+func (self *Tab) readStream(handle string, w io.Writer) (int64, error) {
+	var total int64
+
+	defer self.RPC(`IO`, `close`, map[string]interface{}{
+		`handle`: handle,
+	})
+
+	for {
+		if rv, err := self.RPC(`IO`, `read`, map[string]interface{}{
+			`handle`: handle,
+			`size`:   PdfStreamChunkSize,
+		}); err == nil {
+			out := rv.R()
+			chunk := []byte(out.String(`data`))
+
+			if out.Bool(`base64Encoded`) {
+				if decoded, err := base64.StdEncoding.DecodeString(string(chunk)); err == nil {
+					chunk = decoded
+				} else {
+					return total, fmt.Errorf("decode error: %v", err)
+				}
+			}
+
+			if n, err := w.Write(chunk); err == nil {
+				total += int64(n)
+			} else {
+				return total, err
+			}
+
+			if out.Bool(`eof`) {
+				return total, nil
+			}
+		} else {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package browser
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+)
+
+// Describes how the mouse pointer should travel between two points.  Rather than jumping directly
+// to its destination, the pointer follows a randomly-curved path, accelerating and decelerating
+// along the way, with a small amount of random wobble.
+type PointerPath struct {
+	// How long the movement should take.
+	Duration time.Duration `json:"duration" default:"400ms"`
+
+	// The number of intermediate mouse movements to emit.
+	Steps int `json:"steps" default:"25"`
+
+	// The maximum distance (in pixels) that each intermediate point may randomly deviate from the path.
+	Jitter float64 `json:"jitter" default:"1.5"`
+
+	// How far the path may bow away from a straight line, as a fraction of the distance travelled.
+	Curvature float64 `json:"curvature" default:"0.25"`
+}
+
+// Return the points along the path from one point to another, ending exactly at the destination.
+func (self *PointerPath) Points(fromX float64, fromY float64, toX float64, toY float64) [][2]float64 {
+	dx, dy := toX-fromX, toY-fromY
+	distance := math.Hypot(dx, dy)
+
+	if self.Steps <= 1 || distance < 1 {
+		return [][2]float64{{toX, toY}}
+	}
+
+	points := make([][2]float64, 0, self.Steps)
+
+	// the unit vector perpendicular to the direction of travel
+	px, py := -dy/distance, dx/distance
+
+	// two control points, each bowed off to a random side by a random amount
+	bow := func() float64 {
+		return (rand.Float64()*2 - 1) * self.Curvature * distance
+	}
+
+	b1, b2 := bow(), bow()
+	c1x, c1y := fromX+dx*0.33+px*b1, fromY+dy*0.33+py*b1
+	c2x, c2y := fromX+dx*0.66+px*b2, fromY+dy*0.66+py*b2
+
+	for i := 1; i <= self.Steps; i++ {
+		if i == self.Steps {
+			points = append(points, [2]float64{toX, toY})
+			break
+		}
+
+		t := easeInOut(float64(i) / float64(self.Steps))
+		x := cubicBezier(fromX, c1x, c2x, toX, t)
+		y := cubicBezier(fromY, c1y, c2y, toY, t)
+
+		if self.Jitter > 0 {
+			x += (rand.Float64()*2 - 1) * self.Jitter
+			y += (rand.Float64()*2 - 1) * self.Jitter
+		}
+
+		points = append(points, [2]float64{x, y})
+	}
+
+	return points
+}
+
+// Return how long to wait between each step, varied randomly by up to 25%.
+func (self *PointerPath) StepDelay() time.Duration {
+	if self.Steps <= 0 {
+		return 0
+	}
+
+	delay := float64(self.Duration) / float64(self.Steps)
+
+	return time.Duration(delay * (0.75 + rand.Float64()*0.5))
+}
+
+// Return the last known position of the mouse pointer.
+func (self *Tab) MousePosition() (float64, float64) {
+	return self.mouseX, self.mouseY
+}
+
+// Move the mouse from its current position to the given point along the given path, emitting
+// intermediate movement events along the way.
+func (self *Tab) MoveMouseAlong(x float64, y float64, path *PointerPath, config *MouseActionConfig) error {
+	var move MouseActionConfig
+
+	if config != nil {
+		move = *config
+	}
+
+	if path == nil {
+		path = &PointerPath{}
+		defaults.SetDefaults(path)
+	}
+
+	move.Action = Moved
+	move.Path = nil
+
+	points := path.Points(self.mouseX, self.mouseY, x, y)
+
+	for i, point := range points {
+		if i > 0 {
+			time.Sleep(path.StepDelay())
+		}
+
+		if err := self.MoveMouse(point[0], point[1], &move); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// the position along a cubic Bezier curve at time t
+func cubicBezier(p0 float64, p1 float64, p2 float64, p3 float64, t float64) float64 {
+	u := 1 - t
+	return u*u*u*p0 + 3*u*u*t*p1 + 3*u*t*t*p2 + t*t*t*p3
+}
+
+// slow at the start and end, fast in the middle
+func easeInOut(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
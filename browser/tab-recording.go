@@ -0,0 +1,586 @@
+package browser
+
+import (
+	"bytes"
+	"compress/lzw"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// How long the last frame of a recording is shown for if the recording was stopped immediately
+// after it was captured.
+var MinimumLastFrameDuration = 100 * time.Millisecond
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+type RecordingOptions struct {
+	// The maximum width and height of the captured frames; frames are scaled down to fit.
+	MaxWidth  int
+	MaxHeight int
+
+	// Only capture every Nth frame the browser renders.
+	EveryNthFrame int
+
+	// Stop capturing new frames once this many have been captured (0 means no limit).
+	MaxFrames int
+}
+
+// A single frame of a screencast recording.
+type RecordedFrame struct {
+	// The frame image, as a PNG.
+	Data []byte
+
+	// When the frame was rendered.
+	Timestamp time.Time
+
+	// The size of the frame (in pixels).
+	Width  int
+	Height int
+}
+
+// A screencast recording: every frame the browser rendered between starting and stopping.  The
+// browser only renders frames when something on the page changes, so frames are not evenly spaced
+// in time.
+type Recording struct {
+	Frames    []*RecordedFrame
+	StartedAt time.Time
+	StoppedAt time.Time
+	options   RecordingOptions
+	lock      sync.Mutex
+}
+
+// One frame of an image sequence, as described in its manifest.
+type RecordingManifestFrame struct {
+	File      string    `json:"file"`
+	Timestamp time.Time `json:"timestamp"`
+	Offset    int64     `json:"offset_ms"`
+	Duration  int64     `json:"duration_ms"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+}
+
+// Describes the frames of an image sequence and how long each one is shown for.
+type RecordingManifest struct {
+	StartedAt time.Time                 `json:"started_at"`
+	Duration  int64                     `json:"duration_ms"`
+	Frames    []*RecordingManifestFrame `json:"frames"`
+}
+
+// Start recording every frame rendered by the tab.  If the tab is already being screencast (e.g.:
+// to the debugging UI), frames are taken from the existing screencast, in which case the maximum
+// frame size and frame rate options cannot be used.
+func (self *Tab) StartRecording(options *RecordingOptions) error {
+	if options == nil {
+		options = &RecordingOptions{}
+	}
+
+	self.castlock.Lock()
+	defer self.castlock.Unlock()
+
+	if self.recording != nil {
+		return fmt.Errorf("The tab is already being recorded")
+	}
+
+	recording := &Recording{
+		Frames:    make([]*RecordedFrame, 0),
+		StartedAt: time.Now(),
+		options:   *options,
+	}
+
+	if self.castRefs > 0 {
+		// the frame size and rate are fixed when a screencast starts, so they can't apply to one
+		// that is already running
+		if options.MaxWidth > 0 || options.MaxHeight > 0 || options.EveryNthFrame > 1 {
+			return fmt.Errorf("The tab is already being screencast; the maximum frame size and frame rate cannot be changed")
+		}
+	} else {
+		args := map[string]interface{}{
+			`format`: `png`,
+		}
+
+		if options.MaxWidth > 0 {
+			args[`maxWidth`] = options.MaxWidth
+		}
+
+		if options.MaxHeight > 0 {
+			args[`maxHeight`] = options.MaxHeight
+		}
+
+		if options.EveryNthFrame > 1 {
+			args[`everyNthFrame`] = options.EveryNthFrame
+		}
+
+		if err := self.AsyncRPC(`Page`, `startScreencast`, args); err != nil {
+			return err
+		}
+	}
+
+	self.castRefs += 1
+	self.recording = recording
+	return nil
+}
+
+// Return whether the tab is currently being recorded.
+func (self *Tab) IsRecording() bool {
+	self.castlock.Lock()
+	defer self.castlock.Unlock()
+
+	return (self.recording != nil)
+}
+
+// Stop recording the tab and return the recording.  The screencast is stopped too, unless something
+// else (e.g.: the debugging UI) is still using it.
+func (self *Tab) StopRecording() (*Recording, error) {
+	self.castlock.Lock()
+	defer self.castlock.Unlock()
+
+	recording := self.recording
+
+	if recording == nil {
+		return nil, fmt.Errorf("The tab is not being recorded")
+	}
+
+	self.recording = nil
+
+	recording.lock.Lock()
+	recording.StoppedAt = time.Now()
+	recording.lock.Unlock()
+
+	if err := self.releaseScreencast(); err != nil {
+		return recording, err
+	}
+
+	return recording, nil
+}
+
+// called for each screencast frame received while the tab is being recorded
+func (self *Tab) recordFrame(data []byte, timestamp time.Time, width int, height int) {
+	self.castlock.Lock()
+	recording := self.recording
+	self.castlock.Unlock()
+
+	if recording == nil {
+		return
+	}
+
+	recording.lock.Lock()
+	defer recording.lock.Unlock()
+
+	// frames that arrive after the recording was stopped are dropped
+	if !recording.StoppedAt.IsZero() {
+		return
+	} else if limit := recording.options.MaxFrames; limit > 0 && len(recording.Frames) >= limit {
+		return
+	}
+
+	recording.Frames = append(recording.Frames, &RecordedFrame{
+		Data:      data,
+		Timestamp: timestamp,
+		Width:     width,
+		Height:    height,
+	})
+}
+
+// Return how long the recording ran for.
+func (self *Recording) Duration() time.Duration {
+	if self.StoppedAt.IsZero() {
+		return time.Since(self.StartedAt)
+	}
+
+	return self.StoppedAt.Sub(self.StartedAt)
+}
+
+// Return how long each frame should be shown for: until the next frame was rendered, or (for the
+// last frame) until the recording was stopped.
+func (self *Recording) FrameDurations() []time.Duration {
+	durations := make([]time.Duration, len(self.Frames))
+
+	for i, frame := range self.Frames {
+		var until time.Time
+
+		if i+1 < len(self.Frames) {
+			until = self.Frames[i+1].Timestamp
+		} else {
+			until = self.StoppedAt
+		}
+
+		if d := until.Sub(frame.Timestamp); d > 0 {
+			durations[i] = d
+		}
+
+		if i+1 == len(self.Frames) && durations[i] < MinimumLastFrameDuration {
+			durations[i] = MinimumLastFrameDuration
+		}
+	}
+
+	return durations
+}
+
+// Encode the recording as an animated GIF.  Frames are reduced to a 256-color palette with
+// dithering.  If loop is zero, the animation repeats forever; otherwise it plays that many times.
+// Frames are decoded and encoded one at a time, so long recordings don't need to fit in memory.
+func (self *Recording) EncodeGIF(w io.Writer, loop int) error {
+	bounds, err := self.frameBounds()
+
+	if err != nil {
+		return err
+	}
+
+	colors := color.Palette(palette.Plan9)
+	durations := self.FrameDurations()
+	paletted := image.NewPaletted(bounds, colors)
+
+	// header, logical screen descriptor, and global color table
+	header := []byte("GIF89a\x00\x00\x00\x00\xf7\x00\x00")
+	binary.LittleEndian.PutUint16(header[6:8], uint16(bounds.Dx()))
+	binary.LittleEndian.PutUint16(header[8:10], uint16(bounds.Dy()))
+
+	for _, c := range colors {
+		r, g, b, _ := c.RGBA()
+		header = append(header, byte(r>>8), byte(g>>8), byte(b>>8))
+	}
+
+	// the looping extension; without it, the animation plays once
+	if loop != 1 {
+		header = append(header, "\x21\xff\x0bNETSCAPE2.0\x03\x01\x00\x00\x00"...)
+
+		if loop > 1 {
+			binary.LittleEndian.PutUint16(header[len(header)-3:], uint16(loop-1))
+		}
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	if err := self.eachFrame(bounds, func(i int, frame *image.RGBA) error {
+		draw.FloydSteinberg.Draw(paletted, bounds, frame, image.Point{})
+
+		// GIF delays are in hundredths of a second, and most viewers treat anything less than 2 as 10
+		delay := int(durations[i] / (10 * time.Millisecond))
+
+		if delay < 2 {
+			delay = 2
+		} else if delay > 65535 {
+			delay = 65535
+		}
+
+		// graphic control extension (for the delay), then an image descriptor covering the canvas
+		descriptor := []byte("\x21\xf9\x04\x00\x00\x00\x00\x00\x2c\x00\x00\x00\x00\x00\x00\x00\x00\x00\x08")
+		binary.LittleEndian.PutUint16(descriptor[4:6], uint16(delay))
+		binary.LittleEndian.PutUint16(descriptor[13:15], uint16(bounds.Dx()))
+		binary.LittleEndian.PutUint16(descriptor[15:17], uint16(bounds.Dy()))
+
+		if _, err := w.Write(descriptor); err != nil {
+			return err
+		}
+
+		blocks := &gifBlockWriter{w: w}
+		encoder := lzw.NewWriter(blocks, lzw.LSB, 8)
+
+		if _, err := encoder.Write(paletted.Pix); err != nil {
+			return err
+		}
+
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+
+		return blocks.Close()
+	}); err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte{0x3b})
+	return err
+}
+
+// Encode the recording as an animated PNG (APNG).  Frames are stored losslessly.  If loop is zero,
+// the animation repeats forever; otherwise it plays that many times.  Like EncodeGIF, frames are
+// decoded and encoded one at a time.
+func (self *Recording) EncodeAPNG(w io.Writer, loop int) error {
+	bounds, err := self.frameBounds()
+
+	if err != nil {
+		return err
+	}
+
+	var sequence uint32
+	var buf bytes.Buffer
+	durations := self.FrameDurations()
+
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+
+	if err := self.eachFrame(bounds, func(i int, frame *image.RGBA) error {
+		buf.Reset()
+
+		if err := png.Encode(&buf, frame); err != nil {
+			return err
+		}
+
+		chunks, err := readPngChunks(buf.Bytes())
+
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			// the image header is shared by all frames, and is followed by the animation control chunk
+			for _, chunk := range chunks {
+				if chunk.Type == `IHDR` {
+					if err := writePngChunk(w, `IHDR`, chunk.Data); err != nil {
+						return err
+					}
+				}
+			}
+
+			actl := make([]byte, 8)
+			binary.BigEndian.PutUint32(actl[0:4], uint32(len(self.Frames)))
+			binary.BigEndian.PutUint32(actl[4:8], uint32(loop))
+
+			if err := writePngChunk(w, `acTL`, actl); err != nil {
+				return err
+			}
+		}
+
+		// frame control: sequence, size, offset, delay (in milliseconds), disposal and blending
+		delay := durations[i].Milliseconds()
+
+		if delay > 65535 {
+			delay = 65535
+		}
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:4], sequence)
+		binary.BigEndian.PutUint32(fctl[4:8], uint32(frame.Bounds().Dx()))
+		binary.BigEndian.PutUint32(fctl[8:12], uint32(frame.Bounds().Dy()))
+		binary.BigEndian.PutUint16(fctl[20:22], uint16(delay))
+		binary.BigEndian.PutUint16(fctl[22:24], 1000)
+		sequence += 1
+
+		if err := writePngChunk(w, `fcTL`, fctl); err != nil {
+			return err
+		}
+
+		// the first frame's data is stored as normal image data (so that viewers which don't
+		// support animation show it), subsequent frames as numbered frame data chunks
+		for _, chunk := range chunks {
+			if chunk.Type != `IDAT` {
+				continue
+			}
+
+			if i == 0 {
+				err = writePngChunk(w, `IDAT`, chunk.Data)
+			} else {
+				fdat := make([]byte, 4, 4+len(chunk.Data))
+				binary.BigEndian.PutUint32(fdat, sequence)
+				sequence += 1
+
+				err = writePngChunk(w, `fdAT`, append(fdat, chunk.Data...))
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return writePngChunk(w, `IEND`, nil)
+}
+
+// Write each frame of the recording to the given directory as a numbered PNG file, along with a
+// "manifest.json" file describing when each frame was rendered and how long it was shown for.
+func (self *Recording) WriteSequence(directory string) (*RecordingManifest, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+
+	durations := self.FrameDurations()
+	manifest := &RecordingManifest{
+		StartedAt: self.StartedAt,
+		Duration:  self.Duration().Milliseconds(),
+		Frames:    make([]*RecordingManifestFrame, len(self.Frames)),
+	}
+
+	for i, frame := range self.Frames {
+		filename := fmt.Sprintf("frame-%05d.png", i+1)
+
+		if err := os.WriteFile(filepath.Join(directory, filename), frame.Data, 0644); err != nil {
+			return nil, err
+		}
+
+		manifest.Frames[i] = &RecordingManifestFrame{
+			File:      filename,
+			Timestamp: frame.Timestamp,
+			Offset:    frame.Timestamp.Sub(self.StartedAt).Milliseconds(),
+			Duration:  durations[i].Milliseconds(),
+			Width:     frame.Width,
+			Height:    frame.Height,
+		}
+	}
+
+	if data, err := json.MarshalIndent(manifest, ``, `  `); err == nil {
+		return manifest, os.WriteFile(filepath.Join(directory, `manifest.json`), data, 0644)
+	} else {
+		return nil, err
+	}
+}
+
+// return the size of a canvas large enough to hold the largest frame (only the frames' headers are
+// read)
+func (self *Recording) frameBounds() (image.Rectangle, error) {
+	var width, height int
+
+	if len(self.Frames) == 0 {
+		return image.Rectangle{}, fmt.Errorf("No frames were recorded")
+	}
+
+	for i, frame := range self.Frames {
+		if config, err := png.DecodeConfig(bytes.NewReader(frame.Data)); err == nil {
+			width = max(width, config.Width)
+			height = max(height, config.Height)
+		} else {
+			return image.Rectangle{}, fmt.Errorf("frame %d: %v", i+1, err)
+		}
+	}
+
+	return image.Rect(0, 0, width, height), nil
+}
+
+// decode each frame in turn, drawing it onto an opaque canvas of the given size and passing that
+// to fn.  The same canvas is reused for every frame.
+func (self *Recording) eachFrame(bounds image.Rectangle, fn func(i int, frame *image.RGBA) error) error {
+	canvas := image.NewRGBA(bounds)
+
+	for i, frame := range self.Frames {
+		if img, err := png.Decode(bytes.NewReader(frame.Data)); err == nil {
+			draw.Draw(canvas, bounds, image.White, image.Point{}, draw.Src)
+			draw.Draw(canvas, img.Bounds().Sub(img.Bounds().Min), img, img.Bounds().Min, draw.Over)
+		} else {
+			return fmt.Errorf("frame %d: %v", i+1, err)
+		}
+
+		if err := fn(i, canvas); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writes GIF image data as a series of length-prefixed sub-blocks (of at most 255 bytes)
+type gifBlockWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (self *gifBlockWriter) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		take := min(255-len(self.buf), len(p))
+		self.buf = append(self.buf, p[:take]...)
+		p = p[take:]
+
+		if len(self.buf) == 255 {
+			if err := self.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (self *gifBlockWriter) flush() error {
+	if len(self.buf) > 0 {
+		if _, err := self.w.Write(append([]byte{byte(len(self.buf))}, self.buf...)); err != nil {
+			return err
+		}
+
+		self.buf = self.buf[:0]
+	}
+
+	return nil
+}
+
+// write any remaining data, followed by the block terminator
+func (self *gifBlockWriter) Close() error {
+	if err := self.flush(); err != nil {
+		return err
+	}
+
+	_, err := self.w.Write([]byte{0x00})
+	return err
+}
+
+type pngChunk struct {
+	Type string
+	Data []byte
+}
+
+// split an encoded PNG image into its chunks
+func readPngChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("Not a PNG image")
+	}
+
+	chunks := make([]pngChunk, 0)
+	data = data[len(pngSignature):]
+
+	for len(data) >= 12 {
+		length := int(binary.BigEndian.Uint32(data[0:4]))
+
+		if len(data) < 12+length {
+			return nil, fmt.Errorf("Truncated PNG chunk")
+		}
+
+		chunks = append(chunks, pngChunk{
+			Type: string(data[4:8]),
+			Data: data[8 : 8+length],
+		})
+
+		data = data[12+length:]
+	}
+
+	return chunks, nil
+}
+
+func writePngChunk(w io.Writer, chunkType string, data []byte) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:8], chunkType)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:8])
+	crc.Write(data)
+
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+
+	for _, part := range [][]byte{header, data, footer} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"github.com/ghetzel/go-stockutil/maputil"
+)
+
+// The tallest image (in device pixels) that will be captured in one piece.  Taller captures are
+// taken in tiles of this height and stitched together, since the GPU cannot render surfaces much
+// larger than this.
+var MaxScreenshotTileHeight = 16384
+
+// A rectangle, in CSS pixels.
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+type ScreenshotOptions struct {
+	// The image format to capture; one of "png", "jpeg", or "webp".
+	Format string
+
+	// The compression quality (0-100) for "jpeg" and "webp" formats.
+	Quality int
+
+	// The region of the page (in document coordinates) to capture.  If not given, the whole page is
+	// captured when FullPage is set, otherwise the visible part of the page is captured.
+	Clip *Rect
+
+	// Capture the entire scrollable page rather than just what is currently visible.
+	FullPage bool
+
+	// The device scale factor of the resulting image (e.g.: 2 for a "retina" image).
+	Scale float64
+
+	// Render the page with a transparent background (instead of white).  Only applies to the
+	// "png" and "webp" formats.
+	OmitBackground bool
+}
+
+// Return the visible part of the page (in document coordinates) and the size of the page's
+// content.
+func (self *Tab) LayoutMetrics() (*Rect, *Rect, error) {
+	if rv, err := self.RPC(`Page`, `getLayoutMetrics`, nil); err == nil {
+		out := rv.R()
+		viewport := maputil.M(out.Get(`cssVisualViewport`, out.Get(`visualViewport`).Value))
+		content := maputil.M(out.Get(`cssContentSize`, out.Get(`contentSize`).Value))
+
+		return &Rect{
+			X:      viewport.Float(`pageX`),
+			Y:      viewport.Float(`pageY`),
+			Width:  viewport.Float(`clientWidth`),
+			Height: viewport.Float(`clientHeight`),
+		}, &Rect{
+			X:      content.Float(`x`),
+			Y:      content.Float(`y`),
+			Width:  content.Float(`width`),
+			Height: content.Float(`height`),
+		}, nil
+	} else {
+		return nil, nil, err
+	}
+}
+
+// Set (or clear, if nil) the default background color of the page.  The color is remembered so
+// that it can be restored after temporarily changing it.
+func (self *Tab) SetBackgroundColor(color *HighlightColor) error {
+	var args map[string]interface{}
+
+	if color != nil {
+		args = map[string]interface{}{
+			`color`: color.ToMap(),
+		}
+	}
+
+	if _, err := self.RPC(`Emulation`, `setDefaultBackgroundColorOverride`, args); err != nil {
+		return err
+	}
+
+	self.backgroundColor = color
+	return nil
+}
+
+// Capture an image of the page without resizing the viewport.  Returns the image data and the
+// region of the page (in document coordinates) that was captured.
+func (self *Tab) CaptureScreenshot(options *ScreenshotOptions) ([]byte, *Rect, error) {
+	if options == nil {
+		options = &ScreenshotOptions{}
+	}
+
+	if options.Format == `` {
+		options.Format = `png`
+	}
+
+	if options.Scale <= 0 {
+		options.Scale = 1
+	}
+
+	switch options.Format {
+	case `png`, `jpeg`, `webp`:
+	default:
+		return nil, nil, fmt.Errorf("Unsupported screenshot format %q", options.Format)
+	}
+
+	region := options.Clip
+
+	if region == nil {
+		if viewport, content, err := self.LayoutMetrics(); err == nil {
+			if options.FullPage {
+				region = content
+			} else {
+				region = viewport
+			}
+		} else {
+			return nil, nil, err
+		}
+	}
+
+	if region.Width <= 0 || region.Height <= 0 {
+		return nil, nil, fmt.Errorf("Cannot capture an empty region (%vx%v)", region.Width, region.Height)
+	}
+
+	if options.OmitBackground && options.Format != `jpeg` {
+		previous := self.backgroundColor
+
+		if err := self.SetBackgroundColor(&HighlightColor{}); err != nil {
+			return nil, nil, err
+		}
+
+		defer self.SetBackgroundColor(previous)
+	}
+
+	// capture in one go if the region is small enough
+	if region.Height*options.Scale <= float64(MaxScreenshotTileHeight) {
+		data, err := self.captureRegion(region, options.Format, options.Quality, options.Scale)
+		return data, region, err
+	}
+
+	if options.Format == `webp` {
+		return nil, nil, fmt.Errorf("Pages taller than %dpx can only be captured as png or jpeg", MaxScreenshotTileHeight)
+	}
+
+	// otherwise, capture the region in tiles and stitch them together
+	tileHeight := math.Floor(float64(MaxScreenshotTileHeight) / options.Scale)
+	canvas := image.NewRGBA(image.Rect(
+		0,
+		0,
+		int(math.Ceil(region.Width*options.Scale)),
+		int(math.Ceil(region.Height*options.Scale)),
+	))
+
+	for top := 0.0; top < region.Height; top += tileHeight {
+		tile := &Rect{
+			X:      region.X,
+			Y:      region.Y + top,
+			Width:  region.Width,
+			Height: math.Min(tileHeight, region.Height-top),
+		}
+
+		if data, err := self.captureRegion(tile, `png`, 0, options.Scale); err == nil {
+			if img, err := png.Decode(bytes.NewReader(data)); err == nil {
+				offset := image.Pt(0, int(math.Round(top*options.Scale)))
+				draw.Draw(canvas, img.Bounds().Add(offset), img, img.Bounds().Min, draw.Src)
+			} else {
+				return nil, nil, fmt.Errorf("tile at %v: %v", top, err)
+			}
+		} else {
+			return nil, nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+
+	switch options.Format {
+	case `jpeg`:
+		quality := options.Quality
+
+		if quality <= 0 {
+			quality = jpeg.DefaultQuality
+		}
+
+		err = jpeg.Encode(&buf, canvas, &jpeg.Options{
+			Quality: quality,
+		})
+	default:
+		err = png.Encode(&buf, canvas)
+	}
+
+	return buf.Bytes(), region, err
+}
+
+func (self *Tab) captureRegion(region *Rect, format string, quality int, scale float64) ([]byte, error) {
+	args := map[string]interface{}{
+		`format`:                format,
+		`fromSurface`:           true,
+		`captureBeyondViewport`: true,
+		`clip`: map[string]interface{}{
+			`x`:      region.X,
+			`y`:      region.Y,
+			`width`:  region.Width,
+			`height`: region.Height,
+			`scale`:  scale,
+		},
+	}
+
+	if format != `png` && quality > 0 {
+		args[`quality`] = quality
+	}
+
+	if reply, err := self.RPC(`Page`, `captureScreenshot`, args); err == nil {
+		if data := reply.R().String(`data`); data != `` {
+			return base64.StdEncoding.DecodeString(data)
+		} else {
+			return nil, fmt.Errorf("Empty response received while capturing screenshot")
+		}
+	} else {
+		return nil, err
+	}
+}
@@ -0,0 +1,376 @@
+package browser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghetzel/go-stockutil/log"
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/gobwas/glob"
+)
+
+var CertificateVerifyTimeout = 10 * time.Second
+var ClientCertificateRequestTimeout = 60 * time.Second
+
+type CertificatePolicy struct {
+	// Ignore all certificate errors for all origins.
+	IgnoreAll bool
+
+	// Ignore certificate errors for origins matching any of these patterns.
+	IgnoreOrigins []string
+
+	// Additional certificate authorities that will be trusted when deciding whether a
+	// certificate error should be ignored.
+	RootCAs *x509.CertPool
+
+	origins  []glob.Glob
+	verified sync.Map
+}
+
+func (self *CertificatePolicy) ignores(requestUrl string) bool {
+	if self.IgnoreAll {
+		return true
+	}
+
+	if u, err := url.Parse(requestUrl); err == nil {
+		origin := u.Scheme + `://` + u.Host
+
+		for _, pattern := range self.origins {
+			if pattern.Match(origin) || pattern.Match(u.Host) || pattern.Match(u.Hostname()) {
+				return true
+			}
+		}
+
+		if self.RootCAs != nil {
+			return self.verifyOrigin(u)
+		}
+	}
+
+	return false
+}
+
+// Set the policy for how certificate errors encountered while loading pages should be handled.
+// Passing a nil policy restores the browser's default behavior of failing on errors.
+func (self *Tab) SetCertificatePolicy(policy *CertificatePolicy) error {
+	self.certlock.Lock()
+	defer self.certlock.Unlock()
+
+	if policy == nil {
+		policy = &CertificatePolicy{}
+	}
+
+	policy.origins = nil
+
+	for _, origin := range policy.IgnoreOrigins {
+		if pattern, err := glob.Compile(origin); err == nil {
+			policy.origins = append(policy.origins, pattern)
+		} else {
+			return fmt.Errorf("invalid origin pattern %q: %v", origin, err)
+		}
+	}
+
+	if err := self.AsyncRPC(`Security`, `enable`, nil); err != nil {
+		return err
+	}
+
+	if _, err := self.RPC(`Security`, `setIgnoreCertificateErrors`, map[string]interface{}{
+		`ignore`: policy.IgnoreAll,
+	}); err != nil {
+		return err
+	}
+
+	// per-origin decisions are made as errors are reported to us
+	if _, err := self.RPC(`Security`, `setOverrideCertificateErrors`, map[string]interface{}{
+		`override`: (!policy.IgnoreAll && (len(policy.origins) > 0 || policy.RootCAs != nil)),
+	}); err != nil {
+		return err
+	}
+
+	self.certPolicy = policy
+	return nil
+}
+
+func (self *Tab) handleCertificateError(event *Event) {
+	self.certlock.Lock()
+	policy := self.certPolicy
+	self.certlock.Unlock()
+
+	requestUrl := event.P().String(`requestURL`)
+	action := `cancel`
+
+	if policy != nil && policy.ignores(requestUrl) {
+		action = `continue`
+	}
+
+	log.Debugf("[tab] Certificate error %v for %v: %s", event.P().String(`errorType`), requestUrl, action)
+
+	if err := self.AsyncRPC(`Security`, `handleCertificateError`, map[string]interface{}{
+		`eventId`: event.P().Int(`eventId`),
+		`action`:  action,
+	}); err != nil {
+		log.Errorf("Failed to handle certificate error: %v", err)
+	}
+}
+
+// Present the given client certificate to hosts whose URLs match urlPattern.  Because the browser
+// does not expose client certificate selection via the DevTools protocol, matching requests are
+// intercepted and performed on the browser's behalf using the given certificate.
+func (self *Tab) AddClientCertificate(urlPattern string, certificate tls.Certificate, rootCAs *x509.CertPool) error {
+	var insecure bool
+
+	self.certlock.Lock()
+
+	if policy := self.certPolicy; policy != nil {
+		insecure = policy.IgnoreAll
+
+		if rootCAs == nil {
+			rootCAs = policy.RootCAs
+		}
+	}
+
+	self.certlock.Unlock()
+
+	client := &http.Client{
+		Timeout: ClientCertificateRequestTimeout,
+		Transport: &http.Transport{
+			Proxy: self.clientCertificateProxy,
+			TLSClientConfig: &tls.Config{
+				Certificates:       []tls.Certificate{certificate},
+				RootCAs:            rootCAs,
+				InsecureSkipVerify: insecure,
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// let the browser follow redirects itself
+			return http.ErrUseLastResponse
+		},
+	}
+
+	requestPattern, err := self.addNetworkIntercept(urlPattern, false, func(tab *Tab, pattern *NetworkRequestPattern, event *Event) *NetworkInterceptResponse {
+		response := &NetworkInterceptResponse{}
+		p := event.P()
+
+		req, err := http.NewRequest(
+			p.String(`request.method`, `GET`),
+			p.String(`request.url`),
+			bytes.NewBufferString(p.String(`request.postData`)),
+		)
+
+		if err != nil {
+			response.Error = fmt.Errorf("Failed")
+			return response
+		}
+
+		for k, v := range p.Map(`request.headers`) {
+			req.Header.Set(k.String(), v.String())
+		}
+
+		// intercepted requests don't carry the cookies the browser would have sent with them
+		if req.Header.Get(`Cookie`) == `` {
+			if cookie, err := tab.cookieHeader(req.URL.String()); err == nil && cookie != `` {
+				req.Header.Set(`Cookie`, cookie)
+			} else if err != nil {
+				log.Warningf("Failed to retrieve cookies for %v: %v", req.URL, err)
+			}
+		}
+
+		if res, err := client.Do(req); err == nil {
+			defer res.Body.Close()
+
+			if data, err := ioutil.ReadAll(res.Body); err == nil {
+				res.Header.Del(`Transfer-Encoding`)
+				res.Header.Del(`Content-Length`)
+
+				response.StatusCode = res.StatusCode
+				response.Header = res.Header
+				response.Body = bytes.NewBuffer(data)
+			} else {
+				log.Warningf("Failed to read client certificate response: %v", err)
+				response.Error = fmt.Errorf("Failed")
+			}
+		} else {
+			log.Warningf("Client certificate request failed: %v", err)
+			response.Error = fmt.Errorf("ConnectionFailed")
+		}
+
+		return response
+	})
+
+	if err != nil {
+		return err
+	}
+
+	self.certlock.Lock()
+	self.clientCertIntercepts = append(self.clientCertIntercepts, requestPattern)
+	self.certlock.Unlock()
+
+	return nil
+}
+
+// Stop presenting client certificates added with AddClientCertificate.
+func (self *Tab) ClearClientCertificates() error {
+	self.certlock.Lock()
+	intercepts := self.clientCertIntercepts
+	self.clientCertIntercepts = nil
+	self.certlock.Unlock()
+
+	for _, requestPattern := range intercepts {
+		if err := self.RemoveNetworkIntercept(requestPattern); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// build the Cookie header the browser would send with a request to the given URL
+func (self *Tab) cookieHeader(requestUrl string) (string, error) {
+	if rv, err := self.RPC(`Network`, `getCookies`, map[string]interface{}{
+		`urls`: []string{requestUrl},
+	}); err == nil {
+		pairs := make([]string, 0)
+
+		for _, cookie := range rv.R().Slice(`cookies`) {
+			c := maputil.M(cookie.Value)
+			pairs = append(pairs, c.String(`name`)+`=`+c.String(`value`))
+		}
+
+		return strings.Join(pairs, `; `), nil
+	} else {
+		return ``, err
+	}
+}
+
+// Load all PEM-encoded certificates from the given data into a pool containing the system
+// trusted roots.
+func LoadCertificatePool(pemData []byte) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("no valid PEM certificates found")
+	}
+
+	return pool, nil
+}
+
+// Return the base64-encoded SHA-256 hashes of the SubjectPublicKeyInfo of each PEM-encoded
+// certificate in the given data, suitable for use with IgnoreCertificateErrorsSPKIList.
+func CertificateSPKIHashes(pemData []byte) ([]string, error) {
+	hashes := make([]string, 0)
+
+	for {
+		var block *pem.Block
+
+		if block, pemData = pem.Decode(pemData); block == nil {
+			break
+		} else if block.Type != `CERTIFICATE` {
+			continue
+		}
+
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			hashes = append(hashes, base64.StdEncoding.EncodeToString(sum[:]))
+		} else {
+			return nil, err
+		}
+	}
+
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("no valid PEM certificates found")
+	}
+
+	return hashes, nil
+}
+
+// connect to the given URL's host and verify its certificate chain against the policy's CA pool
+func (self *CertificatePolicy) verifyOrigin(u *url.URL) bool {
+	host := u.Host
+
+	if u.Port() == `` {
+		host = net.JoinHostPort(u.Hostname(), `443`)
+	}
+
+	if ok, seen := self.verified.Load(host); seen {
+		return ok.(bool)
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{
+		Timeout: CertificateVerifyTimeout,
+	}, `tcp`, host, &tls.Config{
+		RootCAs:    self.RootCAs,
+		ServerName: strings.TrimSuffix(u.Hostname(), `.`),
+	})
+
+	if err == nil {
+		conn.Close()
+	} else {
+		log.Debugf("[tab] Certificate for %v not trusted by custom CAs: %v", host, err)
+	}
+
+	self.verified.Store(host, (err == nil))
+	return (err == nil)
+}
+
+// Return the proxy that requests performed on the browser's behalf should be sent through: the
+// tab's own proxy server (if it was created with one), otherwise whatever the environment specifies.
+func (self *Tab) clientCertificateProxy(req *http.Request) (*url.URL, error) {
+	self.proxylock.Lock()
+	server := self.proxyServer
+	bypass := self.proxyBypassList
+	username := self.proxyUsername
+	password := self.proxyPassword
+	self.proxylock.Unlock()
+
+	if server == `` {
+		return http.ProxyFromEnvironment(req)
+	}
+
+	host := req.URL.Hostname()
+
+	for _, pattern := range bypass {
+		pattern = strings.TrimSpace(pattern)
+
+		if pattern == `<local>` {
+			if !strings.Contains(host, `.`) {
+				return nil, nil
+			}
+		} else if g, err := glob.Compile(pattern); err == nil {
+			if g.Match(host) || g.Match(req.URL.Host) {
+				return nil, nil
+			}
+		}
+	}
+
+	// the browser accepts proxy servers without a scheme, which it treats as HTTP proxies
+	if !strings.Contains(server, `://`) {
+		server = `http://` + server
+	}
+
+	proxyUrl, err := url.Parse(server)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid proxy server %q: %v", server, err)
+	}
+
+	if username != `` || password != `` {
+		proxyUrl.User = url.UserPassword(username, password)
+	}
+
+	return proxyUrl, nil
+}
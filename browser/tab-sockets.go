@@ -0,0 +1,199 @@
+package browser
+
+import (
+	"encoding/base64"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ghetzel/go-stockutil/log"
+)
+
+var socketTrackingEvents = `Network.{webSocket*,eventSourceMessageReceived}`
+
+type SocketType string
+
+const (
+	WebSocketType   SocketType = `websocket`
+	EventSourceType            = `eventsource`
+)
+
+type FrameDirection string
+
+const (
+	FrameSent     FrameDirection = `sent`
+	FrameReceived                = `received`
+)
+
+type SocketFrame struct {
+	SocketID  string         `json:"socket"`
+	Direction FrameDirection `json:"direction"`
+	Opcode    int            `json:"opcode,omitempty"`
+	Binary    bool           `json:"binary,omitempty"`
+	Payload   string         `json:"payload"`
+	EventName string         `json:"event,omitempty"`
+	EventID   string         `json:"event_id,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// Returns the frame payload, decoding it from base64 if the frame carried binary data.
+func (self *SocketFrame) Data() []byte {
+	if self.Binary {
+		if data, err := base64.StdEncoding.DecodeString(self.Payload); err == nil {
+			return data
+		}
+	}
+
+	return []byte(self.Payload)
+}
+
+type Socket struct {
+	ID        string
+	Type      SocketType
+	URL       string
+	Status    int
+	Error     string
+	CreatedAt time.Time
+	ClosedAt  time.Time
+	frames    []*SocketFrame
+	framelock sync.Mutex
+}
+
+func (self *Socket) IsOpen() bool {
+	return self.ClosedAt.IsZero()
+}
+
+func (self *Socket) Frames() []*SocketFrame {
+	self.framelock.Lock()
+	defer self.framelock.Unlock()
+
+	frames := make([]*SocketFrame, len(self.frames))
+	copy(frames, self.frames)
+
+	return frames
+}
+
+func (self *Socket) appendFrame(frame *SocketFrame) {
+	self.framelock.Lock()
+	defer self.framelock.Unlock()
+
+	frame.SocketID = self.ID
+	self.frames = append(self.frames, frame)
+}
+
+// Return all WebSocket and EventSource connections seen by this tab, in the order
+// they were created.
+func (self *Tab) Sockets() []*Socket {
+	sockets := make([]*Socket, 0)
+
+	self.sockets.Range(func(_ interface{}, value interface{}) bool {
+		if socket, ok := value.(*Socket); ok {
+			sockets = append(sockets, socket)
+		}
+
+		return true
+	})
+
+	sort.Slice(sockets, func(i int, j int) bool {
+		return sockets[i].CreatedAt.Before(sockets[j].CreatedAt)
+	})
+
+	return sockets
+}
+
+func (self *Tab) GetSocket(id string) (*Socket, bool) {
+	if value, ok := self.sockets.Load(id); ok {
+		return value.(*Socket), true
+	}
+
+	return nil, false
+}
+
+func (self *Tab) ResetSockets() {
+	self.sockets = sync.Map{}
+}
+
+func (self *Tab) getOrCreateSocket(id string, stype SocketType, event *Event) *Socket {
+	if socket, ok := self.GetSocket(id); ok {
+		return socket
+	}
+
+	socket := &Socket{
+		ID:        id,
+		Type:      stype,
+		CreatedAt: event.Timestamp,
+	}
+
+	// EventSource connections are regular network requests, so pull the URL from there
+	if stype == EventSourceType {
+		if netreq := self.GetLoaderRequest(id); netreq != nil && netreq.Request != nil {
+			socket.URL = netreq.Request.P().String(`request.url`)
+		}
+	}
+
+	self.sockets.Store(id, socket)
+	return socket
+}
+
+func (self *Tab) handleSocketEvent(event *Event) {
+	requestId := event.P().String(`requestId`)
+
+	if requestId == `` {
+		return
+	}
+
+	switch event.Name {
+	case `Network.eventSourceMessageReceived`:
+		socket := self.getOrCreateSocket(requestId, EventSourceType, event)
+
+		socket.appendFrame(&SocketFrame{
+			Direction: FrameReceived,
+			Payload:   event.P().String(`data`),
+			EventName: event.P().String(`eventName`),
+			EventID:   event.P().String(`eventId`),
+			Timestamp: event.Timestamp,
+		})
+
+		log.Debugf("[tab] EventSource[%v] message received", requestId)
+
+	case `Network.webSocketCreated`:
+		socket := self.getOrCreateSocket(requestId, WebSocketType, event)
+		socket.URL = event.P().String(`url`)
+
+		log.Debugf("[tab] WebSocket[%v] created: %v", requestId, socket.URL)
+
+	case `Network.webSocketHandshakeResponseReceived`:
+		socket := self.getOrCreateSocket(requestId, WebSocketType, event)
+		socket.Status = int(event.P().Int(`response.status`))
+
+	case `Network.webSocketFrameSent`, `Network.webSocketFrameReceived`:
+		socket := self.getOrCreateSocket(requestId, WebSocketType, event)
+		opcode := int(event.P().Int(`response.opcode`))
+		frame := &SocketFrame{
+			Direction: FrameReceived,
+			Opcode:    opcode,
+			Binary:    (opcode == 2),
+			Payload:   event.P().String(`response.payloadData`),
+			Timestamp: event.Timestamp,
+		}
+
+		if event.Name == `Network.webSocketFrameSent` {
+			frame.Direction = FrameSent
+		}
+
+		socket.appendFrame(frame)
+		log.Debugf("[tab] WebSocket[%v] frame %s (%d bytes)", requestId, frame.Direction, len(frame.Payload))
+
+	case `Network.webSocketFrameError`:
+		socket := self.getOrCreateSocket(requestId, WebSocketType, event)
+		socket.Error = event.P().String(`errorMessage`)
+
+		log.Debugf("[tab] WebSocket[%v] error: %v", requestId, socket.Error)
+
+	case `Network.webSocketClosed`:
+		socket := self.getOrCreateSocket(requestId, WebSocketType, event)
+		socket.ClosedAt = event.Timestamp
+
+		log.Debugf("[tab] WebSocket[%v] closed", requestId)
+	}
+}
@@ -0,0 +1,167 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+var readTableFn = `
+	var table = this;
+
+	if (table.tagName !== 'TABLE') {
+		table = (table.querySelector('table') || table.closest('table'));
+	}
+
+	if (!table) {
+		throw new Error('element is not (and does not contain) a table');
+	}
+
+	var rows = Array.from(table.rows);
+	var grid = rows.map(function(){ return [] });
+	var headers = 0;
+	var leading = true;
+
+	rows.forEach(function(row, r) {
+		var c = 0;
+		var cells = Array.from(row.cells);
+		var sectionEnd = r + (row.parentElement.rows ? row.parentElement.rows.length - row.sectionRowIndex : 1);
+
+		cells.forEach(function(cell) {
+			while (grid[r][c] !== undefined) { c++; }
+
+			var text = (cell.innerText || cell.textContent || '').replace(/\s+/g, ' ').trim();
+			var colspan = Math.max(1, cell.colSpan || 1);
+
+			// a rowspan of 0 extends the cell to the end of its section
+			var rowspan = (cell.rowSpan === 0 ? sectionEnd - r : Math.max(1, cell.rowSpan || 1));
+
+			for (var i = 0; i < rowspan && (r + i) < grid.length; i++) {
+				for (var j = 0; j < colspan; j++) {
+					grid[r + i][c + j] = text;
+				}
+			}
+
+			c += colspan;
+		});
+
+		// header rows are the leading rows in a <thead>, or that consist only of <th> cells
+		if (leading && (row.parentElement.tagName === 'THEAD' || (cells.length && cells.every(function(cell){
+			return cell.tagName === 'TH';
+		})))) {
+			headers++;
+		} else {
+			leading = false;
+		}
+	});
+
+	return JSON.stringify({
+		grid:    grid.map(function(row){ return Array.from(row, function(v){ return (v === undefined ? '' : v) }) }),
+		headers: headers,
+	});
+`
+
+type Table struct {
+	// The name of each column, derived from the header rows.
+	Headers []string
+
+	// The text of each cell in the table body, with cells that span multiple rows or columns
+	// repeated in each row and column they cover.
+	Rows [][]string
+}
+
+// Return a map of each row's values keyed by column name.
+func (self *Table) Maps() []map[string]interface{} {
+	out := make([]map[string]interface{}, 0)
+
+	for _, row := range self.Rows {
+		rowM := make(map[string]interface{})
+
+		for i, header := range self.Headers {
+			if i < len(row) {
+				rowM[header] = row[i]
+			} else {
+				rowM[header] = ``
+			}
+		}
+
+		out = append(out, rowM)
+	}
+
+	return out
+}
+
+// Read the contents of the given <table> element (or the first table inside of it).  If
+// headerRows is zero, the header rows are detected automatically; if none are found, the first
+// row is used.  Header rows spanning multiple lines are combined (e.g.: "2019 / Revenue").
+func (self *Tab) ReadTable(element *dom.Element, headerRows int) (*Table, error) {
+	var parsed struct {
+		Grid    [][]string `json:"grid"`
+		Headers int        `json:"headers"`
+	}
+
+	if result, err := self.EvaluateOn(element, readTableFn); err == nil {
+		if err := json.Unmarshal([]byte(typeutil.String(result)), &parsed); err != nil {
+			return nil, fmt.Errorf("Invalid table data: %v", err)
+		}
+	} else {
+		return nil, err
+	}
+
+	if headerRows <= 0 {
+		if headerRows = parsed.Headers; headerRows == 0 {
+			headerRows = 1
+		}
+	}
+
+	if headerRows > len(parsed.Grid) {
+		headerRows = len(parsed.Grid)
+	}
+
+	table := &Table{
+		Headers: make([]string, 0),
+		Rows:    parsed.Grid[headerRows:],
+	}
+
+	columns := 0
+
+	for _, row := range parsed.Grid {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	seen := make(map[string]int)
+
+	for c := 0; c < columns; c++ {
+		parts := make([]string, 0)
+
+		for _, row := range parsed.Grid[:headerRows] {
+			// cells spanning multiple header rows only contribute to the name once
+			if c < len(row) && row[c] != `` && (len(parts) == 0 || parts[len(parts)-1] != row[c]) {
+				parts = append(parts, row[c])
+			}
+		}
+
+		header := strings.Join(parts, ` / `)
+
+		if header == `` {
+			header = fmt.Sprintf("column_%d", c+1)
+		}
+
+		// make sure that every column name is unique
+		if n := seen[header]; n > 0 {
+			seen[header] = n + 1
+			header = fmt.Sprintf("%s_%d", header, n+1)
+		} else {
+			seen[header] = 1
+		}
+
+		table.Headers = append(table.Headers, header)
+	}
+
+	return table, nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image/png"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -63,6 +64,7 @@ type NetworkInterceptResponse struct {
 	Body         io.Reader
 	PostData     map[string]interface{}
 	Header       http.Header
+	StatusCode   int
 	Error        error
 	AuthResponse string
 	Username     string
@@ -93,8 +95,14 @@ func (self *NetworkInterceptResponse) ToMap(id string) map[string]interface{} {
 				rv[`headers`] = self.Header
 			}
 		} else if data, err := ioutil.ReadAll(self.Body); err == nil {
+			status := self.StatusCode
+
+			if status <= 0 {
+				status = http.StatusOK
+			}
+
 			raw := &http.Response{
-				StatusCode:    200,
+				StatusCode:    status,
 				Proto:         `HTTP/1.1`,
 				ProtoMajor:    1,
 				ProtoMinor:    1,
@@ -185,14 +193,47 @@ type Tab struct {
 	events               chan *Event
 	waiters              sync.Map
 	networkRequests      sync.Map
+	sockets              sync.Map
 	accumulators         sync.Map
 	mostRecentFrameId    int64
 	mostRecentFrame      []byte
 	mostRecentDimensions []int
-	screencasting        bool
+	castRefs             int
+	castHeld             bool
+	recording            *Recording
 	castlock             sync.Mutex
 	mostRecentInfo       *PageInfo
 	netIntercepts        sync.Map
+	certPolicy           *CertificatePolicy
+	certlock             sync.Mutex
+	clientCertIntercepts []*NetworkRequestPattern
+	downloads            sync.Map
+	downloadlock         sync.Mutex
+	downloadDirectory    string
+	downloadNamedByID    bool
+	browserContextId     string
+	closing              bool
+	proxyServer          string
+	proxyBypassList      []string
+	proxyUsername        string
+	proxyPassword        string
+	proxyAuthEnabled     bool
+	proxylock            sync.Mutex
+	root                 *dom.Element
+	frames               sync.Map
+	activeFrameId        string
+	accessibilityEnabled bool
+	heldModifiers        int
+	keysDown             sync.Map
+	mouseButtons         int
+	mouseX               float64
+	mouseY               float64
+	heldButton           Button
+	touchEmulation       bool
+	backgroundColor      *HighlightColor
+	deviceMetrics        map[string]interface{}
+	metricsScriptId      string
+	metricslock          sync.Mutex
 }
 
 func newTabFromTarget(browser *Browser, target *devtool.Target) (*Tab, error) {
@@ -230,6 +271,8 @@ func (self *Tab) Emit(method string, params map[string]interface{}) {
 }
 
 func (self *Tab) Navigate(url string) (*RpcMessage, error) {
+	self.SwitchFrame(nil)
+
 	self.mostRecentInfo = &PageInfo{
 		URL:   url,
 		State: `initial`,
@@ -239,7 +282,7 @@ func (self *Tab) Navigate(url string) (*RpcMessage, error) {
 		`url`: url,
 	})
 
-	result, err := self.browser.Tab().RPC(`Page`, `navigate`, map[string]interface{}{
+	result, err := self.RPC(`Page`, `navigate`, map[string]interface{}{
 		`url`: url,
 	})
 
@@ -253,36 +296,46 @@ func (self *Tab) Navigate(url string) (*RpcMessage, error) {
 	return result, err
 }
 
+// Start screencasting the tab (e.g.: to the debugging UI).  The screencast is shared with any
+// recording of the tab, so it keeps running until both have stopped using it.
 func (self *Tab) StartScreencast(quality int, width int, height int) error {
 	self.castlock.Lock()
 	defer self.castlock.Unlock()
 
-	if self.screencasting {
+	if self.castHeld {
+		return nil
+	} else if self.castRefs > 0 {
+		self.castHeld = true
+		self.castRefs += 1
 		return nil
-	} else {
-		self.screencasting = true
 	}
 
-	return self.AsyncRPC(`Page`, `startScreencast`, map[string]interface{}{
+	if err := self.AsyncRPC(`Page`, `startScreencast`, map[string]interface{}{
 		`format`:    `png`,
 		`quality`:   int(mathutil.Clamp(float64(quality), 0, 100)),
 		`maxWidth`:  width,
 		`maxHeight`: height,
-	})
+	}); err != nil {
+		return err
+	}
+
+	self.castHeld = true
+	self.castRefs = 1
+	return nil
 }
 
 func (self *Tab) IsScreencasting() bool {
 	self.castlock.Lock()
 	defer self.castlock.Unlock()
 
-	return self.screencasting
+	return (self.castRefs > 0)
 }
 
 func (self *Tab) GetMostRecentFrame() (int64, []byte, int, int) {
 	self.castlock.Lock()
 	defer self.castlock.Unlock()
 
-	if self.screencasting {
+	if self.castRefs > 0 {
 		if d := self.mostRecentDimensions; len(d) == 2 {
 			return self.mostRecentFrameId, self.mostRecentFrame, d[0], d[1]
 		}
@@ -296,18 +349,33 @@ func (self *Tab) ResetMostRecentFrame() {
 	self.mostRecentFrame = nil
 }
 
+// Stop screencasting the tab.  The screencast continues if the tab is still being recorded.
 func (self *Tab) StopScreencast() error {
 	self.castlock.Lock()
 	defer self.castlock.Unlock()
 
-	if self.screencasting {
-		self.screencasting = false
-		return self.AsyncRPC(`Page`, `stopScreencast`, nil)
+	if self.castHeld {
+		self.castHeld = false
+		return self.releaseScreencast()
 	} else {
 		return nil
 	}
 }
 
+// release one reference to the running screencast, stopping it once nothing is using it.  The
+// caller must hold castlock.
+func (self *Tab) releaseScreencast() error {
+	if self.castRefs > 0 {
+		self.castRefs -= 1
+
+		if self.castRefs == 0 {
+			return self.AsyncRPC(`Page`, `stopScreencast`, nil)
+		}
+	}
+
+	return nil
+}
+
 func (self *Tab) connect() error {
 	if conn, err := NewRPC(self.target.WebSocketDebuggerURL); err == nil {
 		self.rpc = conn
@@ -335,14 +403,20 @@ func (self *Tab) CreateAccumulator(filter string) (*eventAccumulator, error) {
 }
 
 func (self *Tab) AsyncRPC(module string, method string, args map[string]interface{}) error {
-	return self.rpc.CallAsync(
+	return self.rpc.CallAsyncInSession(
+		self.sessionFor(module),
 		fmt.Sprintf("%s.%s", module, method),
 		args,
 	)
 }
 
 func (self *Tab) RPC(module string, method string, args map[string]interface{}) (*RpcMessage, error) {
-	if reply, err := self.rpc.Call(fmt.Sprintf("%s.%s", module, method), args, DefaultReplyTimeout); err == nil {
+	return self.sessionRPC(self.sessionFor(module), module, method, args)
+}
+
+// call a method in the given session, or in the tab's own session if sessionId is empty
+func (self *Tab) sessionRPC(sessionId string, module string, method string, args map[string]interface{}) (*RpcMessage, error) {
+	if reply, err := self.rpc.CallInSession(sessionId, fmt.Sprintf("%s.%s", module, method), args, DefaultReplyTimeout); err == nil {
 		return reply, nil
 	} else {
 		return nil, err
@@ -364,6 +438,10 @@ func (self *Tab) setupEvents() error {
 		return err
 	}
 
+	if err := self.rpc.CallAsync(`Runtime.enable`, nil); err != nil {
+		return err
+	}
+
 	if err := self.rpc.CallAsync(`DOM.enable`, nil); err != nil {
 		return err
 	}
@@ -380,6 +458,17 @@ func (self *Tab) setupEvents() error {
 		return err
 	}
 
+	// attach to out-of-process iframes so that their documents can be reached (see SwitchFrame)
+	if err := self.rpc.CallAsync(`Target.setAutoAttach`, frameAutoAttachArgs); err != nil {
+		return err
+	}
+
+	if dir := self.browser.DownloadDirectory; dir != `` {
+		if err := self.EnableDownloads(dir); err != nil {
+			log.Warningf("[tab] Failed to enable downloads: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -447,7 +536,10 @@ func (self *Tab) registerInternalEvents() {
 
 	// ruh roh
 	self.RegisterEventHandler(`Inspector.detached`, func(event *Event) {
-		self.browser.stopWithError(event.Error)
+		// sessions attached to out-of-process iframes come and go along with their frames
+		if !self.closing && event.SessionID == `` {
+			self.browser.stopWithError(event.Error)
+		}
 	})
 
 	self.RegisterEventHandler(netTrackingEvents, func(event *Event) {
@@ -482,6 +574,8 @@ func (self *Tab) registerInternalEvents() {
 		self.networkRequests.Store(requestId, request)
 	})
 
+	self.RegisterEventHandler(socketTrackingEvents, self.handleSocketEvent)
+
 	// monitor page URL and load state
 	self.RegisterEventHandler(`Network.requestWillBeSent`, func(event *Event) {
 		if p := event.P(); p != nil {
@@ -560,12 +654,34 @@ func (self *Tab) registerInternalEvents() {
 					int(event.Params.Int(`metadata.deviceWidth`)),
 					int(event.Params.Int(`metadata.deviceHeight`)),
 				}
+
+				timestamp := time.Now()
+
+				if ts := event.Params.Float(`metadata.timestamp`); ts > 0 {
+					timestamp = time.Unix(0, int64(ts*float64(time.Second)))
+				}
+
+				// the device size differs from the frame's when the screencast is scaled down, so the
+				// recorded size is read from the image itself
+				width, height := self.mostRecentDimensions[0], self.mostRecentDimensions[1]
+
+				if config, err := png.DecodeConfig(bytes.NewReader(decoded)); err == nil {
+					width, height = config.Width, config.Height
+				}
+
+				self.recordFrame(decoded, timestamp, width, height)
 			} else {
 				log.Warningf("[rpc] Failed to decode screencast frame: %v", err)
 			}
 		}
 	})
 
+	self.RegisterEventHandler(`Security.certificateError`, self.handleCertificateError)
+	self.RegisterEventHandler(downloadTrackingEvents, self.handleDownloadEvent)
+	self.RegisterEventHandler(frameTrackingEvents, self.handleFrameEvent)
+	self.RegisterEventHandler(contextTrackingEvents, self.handleExecutionContextEvent)
+	self.RegisterEventHandler(targetTrackingEvents, self.handleTargetEvent)
+
 	// TODO: do something about dialogs, but for now, we're going to auto-cancel them.
 	self.RegisterEventHandler(`Page.javascriptDialogOpening`, func(event *Event) {
 		self.RPC(`Page`, `handleJavaScriptDialog`, map[string]interface{}{
@@ -575,6 +691,20 @@ func (self *Tab) registerInternalEvents() {
 }
 
 func (self *Tab) AddNetworkIntercept(urlPattern string, waitForHeaders bool, fn NetworkInterceptFunc) error {
+	_, err := self.addNetworkIntercept(urlPattern, waitForHeaders, fn)
+	return err
+}
+
+// Remove the intercept registered with the given pattern, leaving all others in place.
+func (self *Tab) RemoveNetworkIntercept(requestPattern *NetworkRequestPattern) error {
+	self.netIntercepts.Delete(requestPattern)
+
+	return self.AsyncRPC(`Network`, `setRequestInterception`, map[string]interface{}{
+		`patterns`: self.networkInterceptPatterns(),
+	})
+}
+
+func (self *Tab) addNetworkIntercept(urlPattern string, waitForHeaders bool, fn NetworkInterceptFunc) (*NetworkRequestPattern, error) {
 	requestPattern := &NetworkRequestPattern{}
 
 	if urlPattern == `` {
@@ -589,9 +719,23 @@ func (self *Tab) AddNetworkIntercept(urlPattern string, waitForHeaders bool, fn
 		requestPattern.InterceptionStage = `Request`
 	}
 
-	patterns := []map[string]interface{}{
+	patterns := append([]map[string]interface{}{
 		requestPattern.ToMap(),
+	}, self.networkInterceptPatterns()...)
+
+	if err := self.AsyncRPC(`Network`, `setRequestInterception`, map[string]interface{}{
+		`patterns`: patterns,
+	}); err == nil {
+		self.netIntercepts.Store(requestPattern, fn)
+		return requestPattern, nil
+	} else {
+		return nil, err
 	}
+}
+
+// the patterns of all currently-registered intercepts
+func (self *Tab) networkInterceptPatterns() []map[string]interface{} {
+	patterns := make([]map[string]interface{}, 0)
 
 	self.netIntercepts.Range(func(key interface{}, _ interface{}) bool {
 		if rp, ok := key.(*NetworkRequestPattern); ok {
@@ -601,16 +745,72 @@ func (self *Tab) AddNetworkIntercept(urlPattern string, waitForHeaders bool, fn
 		return true
 	})
 
-	if err := self.AsyncRPC(`Network`, `setRequestInterception`, map[string]interface{}{
-		`patterns`: patterns,
+	return patterns
+}
+
+// Provide the given credentials whenever a proxy server requests authentication.  Proxy
+// challenges are answered via the Fetch domain, so they are unaffected by (and do not affect) any
+// network intercepts added to the tab.
+func (self *Tab) SetProxyCredentials(username string, password string) error {
+	self.proxylock.Lock()
+	defer self.proxylock.Unlock()
+
+	self.proxyUsername = username
+	self.proxyPassword = password
+
+	if self.proxyAuthEnabled {
+		return nil
+	}
+
+	self.RegisterEventHandler(`Fetch.requestPaused`, self.handleProxyRequestPaused)
+	self.RegisterEventHandler(`Fetch.authRequired`, self.handleProxyAuthRequired)
+
+	// every request has to be paused for the browser to report authentication challenges to us
+	if _, err := self.RPC(`Fetch`, `enable`, map[string]interface{}{
+		`handleAuthRequests`: true,
+		`patterns`: []map[string]interface{}{
+			{
+				`urlPattern`: `*`,
+			},
+		},
 	}); err == nil {
-		self.netIntercepts.Store(requestPattern, fn)
+		self.proxyAuthEnabled = true
 		return nil
 	} else {
 		return err
 	}
 }
 
+func (self *Tab) handleProxyRequestPaused(event *Event) {
+	if err := self.AsyncRPC(`Fetch`, `continueRequest`, map[string]interface{}{
+		`requestId`: event.P().String(`requestId`),
+	}); err != nil {
+		log.Errorf("Failed to continue paused request: %v", err)
+	}
+}
+
+func (self *Tab) handleProxyAuthRequired(event *Event) {
+	response := map[string]interface{}{
+		`response`: `Default`,
+	}
+
+	// challenges from the sites themselves are left to the browser (and any network intercepts)
+	if event.P().String(`authChallenge.source`) == `Proxy` {
+		self.proxylock.Lock()
+		response[`response`] = `ProvideCredentials`
+		response[`username`] = self.proxyUsername
+		response[`password`] = self.proxyPassword
+		self.proxylock.Unlock()
+	}
+
+	if err := self.AsyncRPC(`Fetch`, `continueWithAuth`, map[string]interface{}{
+		`requestId`:             event.P().String(`requestId`),
+		`authChallengeResponse`: response,
+	}); err != nil {
+		log.Errorf("Failed to respond to authentication challenge: %v", err)
+	}
+}
+
 func (self *Tab) ClearNetworkIntercepts() error {
 	self.netIntercepts = sync.Map{}
 
@@ -817,28 +1017,47 @@ func (self *Tab) PageSize() (float64, float64, error) {
 	}
 }
 
+// Override the size and scale of the viewport (see Emulation.setDeviceMetricsOverride).  Passing
+// nil clears the override.  The override is remembered so that it can be restored after being
+// temporarily changed.
+func (self *Tab) SetDeviceMetrics(metrics map[string]interface{}) error {
+	if metrics == nil {
+		if _, err := self.RPC(`Emulation`, `clearDeviceMetricsOverride`, nil); err != nil {
+			return err
+		}
+	} else if _, err := self.RPC(`Emulation`, `setDeviceMetricsOverride`, metrics); err != nil {
+		return err
+	}
+
+	self.deviceMetrics = metrics
+	return nil
+}
+
+// Return the most recently set viewport override (or nil if there is none).
+func (self *Tab) DeviceMetrics() map[string]interface{} {
+	return self.deviceMetrics
+}
+
 func (self *Tab) ElementQuery(selector dom.Selector, parent *dom.Selector) ([]*dom.Element, error) {
-	psel := `document`
+	root := self.Root()
 
 	if parent != nil {
 		if parent.IsNone() {
 			return make([]*dom.Element, 0), nil
+		} else if parents, err := self.ElementQuery(*parent, nil); err == nil {
+			if len(parents) == 0 {
+				return make([]*dom.Element, 0), nil
+			}
+
+			root = parents[0]
 		} else {
-			psel = fmt.Sprintf("document.querySelector(%q)", parent.String())
+			return nil, fmt.Errorf("parent: %v", err)
 		}
 	}
 
-	if results, err := self.Evaluate(fmt.Sprintf(
-		"return Array.from(%s.querySelectorAll(%q))",
-		psel,
-		selector,
-	)); err == nil {
-		elements := make([]*dom.Element, 0)
-
-		for _, result := range sliceutil.Sliceify(results) {
-			if element, ok := result.(*dom.Element); ok {
-				elements = append(elements, element)
-			}
+	if elements, err := self.queryWithin(selector, root); err == nil {
+		if err := self.DescribeElements(elements); err != nil {
+			return nil, err
 		}
 
 		return elements, nil
@@ -861,6 +1080,63 @@ func (self *Tab) EvaluateOn(element *dom.Element, stmt string, exposed ...string
 	}
 }
 
+type evaluateOptions struct {
+	// wait for the result to resolve if it is a promise
+	awaitPromise bool
+
+	// evaluate in the top-level document instead of the active frame
+	topLevel bool
+
+	// treat the evaluation as though it were initiated by the user
+	userGesture bool
+}
+
+// evaluate the given expression (without any of the bindings that Evaluate provides) and return
+// its result by value
+func (self *Tab) evaluateValue(expr string, options *evaluateOptions) (interface{}, error) {
+	var sessionId string
+
+	if options == nil {
+		options = &evaluateOptions{}
+	}
+
+	evalArgs := map[string]interface{}{
+		`expression`:    expr,
+		`returnByValue`: true,
+		`awaitPromise`:  options.awaitPromise,
+		`userGesture`:   options.userGesture,
+	}
+
+	if !options.topLevel {
+		sessionId = self.sessionFor(`Runtime`)
+
+		if contextId, err := self.activeContextId(); err == nil {
+			if contextId > 0 {
+				evalArgs[`contextId`] = contextId
+			}
+		} else {
+			return nil, err
+		}
+	}
+
+	if rv, err := self.sessionRPC(sessionId, `Runtime`, `evaluate`, evalArgs); err == nil {
+		out := rv.R()
+
+		if exc := out.Get(`exceptionDetails`); !exc.IsZero() {
+			excM := maputil.M(exc)
+
+			return nil, fmt.Errorf(
+				"Evaluation error: %v",
+				excM.String(`exception.description`, excM.String(`text`)),
+			)
+		}
+
+		return out.Get(`result.value`).Value, nil
+	} else {
+		return nil, err
+	}
+}
+
 func (self *Tab) evaluate(remoteObjectId string, stmt string, exposed []string) (interface{}, error) {
 	callGroupId := stringutil.UUID().Base58()
 
@@ -873,7 +1149,7 @@ func (self *Tab) evaluate(remoteObjectId string, stmt string, exposed []string)
 
 	// oid <= 0 means call globally
 	if remoteObjectId == `` {
-		rv, err = self.RPC(`Runtime`, `evaluate`, map[string]interface{}{
+		evalArgs := map[string]interface{}{
 			`expression`: fmt.Sprintf(
 				"%s;\nvar fn_%s = function(){ %s }.bind(webfriend); fn_%s()",
 				self.getEvalPrescript(exposed),
@@ -884,7 +1160,18 @@ func (self *Tab) evaluate(remoteObjectId string, stmt string, exposed []string)
 			`returnByValue`: false,
 			`awaitPromise`:  false,
 			`objectGroup`:   callGroupId,
-		})
+		}
+
+		// evaluate inside of the active frame (if any)
+		if contextId, err := self.activeContextId(); err == nil {
+			if contextId > 0 {
+				evalArgs[`contextId`] = contextId
+			}
+		} else {
+			return nil, err
+		}
+
+		rv, err = self.RPC(`Runtime`, `evaluate`, evalArgs)
 	} else {
 		rv, err = self.RPC(`Runtime`, `callFunctionOn`, map[string]interface{}{
 			`objectId`: remoteObjectId,
@@ -920,8 +1207,8 @@ func (self *Tab) evaluate(remoteObjectId string, stmt string, exposed []string)
 		} else {
 			return nil, nil
 		}
-	} else if strings.Contains(err.Error(), `Could not find object with given id`) {
-		return nil, fmt.Errorf("Element '%v' could not be found", remoteObjectId)
+	} else if remoteObjectId != `` && isStaleObjectErr(err) {
+		return nil, dom.StaleElementErr(remoteObjectId)
 	} else {
 		return nil, err
 	}
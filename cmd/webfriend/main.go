@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
@@ -82,6 +83,16 @@ func main() {
 			Name:  `execute, e`,
 			Usage: `Execute the given argument as a Friendscript in the connected session, then exit.`,
 		},
+		cli.BoolFlag{
+			Name:   `ignore-certificate-errors, k`,
+			Usage:  `Ignore TLS certificate errors for all pages loaded by the browser.`,
+			EnvVar: `WEBFRIEND_IGNORE_CERT_ERRORS`,
+		},
+		cli.StringFlag{
+			Name:   `ca-bundle`,
+			Usage:  `The path to a file containing additional PEM-encoded certificate authorities to trust.`,
+			EnvVar: `WEBFRIEND_CA_BUNDLE`,
+		},
 		cli.DurationFlag{
 			Name:  `retrieve-timeout`,
 			Usage: `Specifies the timeout for retrieving runnable scripts from remote sources (e.g.: HTTP)`,
@@ -107,6 +118,17 @@ func main() {
 		chrome.RemoteDebuggingPort = c.Int(`remote-debugging-port`)
 		chrome.RemoteAddress = c.String(`remote-debugging-address`)
 		chrome.StartWait = c.Duration(`start-wait-time`)
+		chrome.IgnoreCertificateErrors = c.Bool(`ignore-certificate-errors`)
+
+		if bundle := c.String(`ca-bundle`); bundle != `` {
+			if pemData, err := ioutil.ReadFile(bundle); err == nil {
+				if err := chrome.TrustCertificateAuthorities(pemData); err != nil {
+					log.Fatalf("invalid CA bundle: %v", err)
+				}
+			} else {
+				log.Fatalf("could not read CA bundle: %v", err)
+			}
+		}
 
 		if err := chrome.Launch(); err == nil {
 			// evaluate Friendscript / run the REPL
@@ -1,8 +1,6 @@
 package core
 
 import (
-	"fmt"
-	"regexp"
 	"time"
 
 	defaults "github.com/ghetzel/go-defaults"
@@ -11,6 +9,9 @@ import (
 	"github.com/ghetzel/go-webfriend/utils"
 )
 
+// how often to re-check whether an element has become clickable
+var actionabilityInterval = 100 * time.Millisecond
+
 type ClickArgs struct {
 	// Permit multiple elements to be clicked.
 	Multiple bool `json:"value"`
@@ -20,11 +21,36 @@ type ClickArgs struct {
 
 	// If provided, this represents a regular expression that the text value of matching elements must match to be clicked.
 	MatchText string `json:"match_text"`
+
+	// Wait for matching elements to reach this state before clicking them (see wait_for_element).
+	WaitFor string `json:"wait_for" default:"attached"`
+
+	// The timeout before we stop waiting for matching elements, and for them to become clickable.
+	Timeout time.Duration `json:"timeout" default:"5s"`
+
+	// Which mouse button to click with; one of "left", "middle", or "right".
+	Button string `json:"button" default:"left"`
+
+	// How many times to click (e.g.: 2 for a double-click).
+	Count int `json:"count" default:"1"`
+
+	// Skip the visibility, enabled, and hit testing checks and call the element's click() method
+	// directly instead of clicking it with the mouse.
+	Force bool `json:"force"`
+
+	// Move the pointer to the element along a human-like path rather than jumping straight there
+	// (see the "path" option of mouse).
+	Path interface{} `json:"path"`
 }
 
 // Click on HTML element(s) matches by selector.  If multiple is true, then all
 // elements matched by selector will be clicked in the order they are returned.
 // Otherwise, an error is returned unless selector matches exactly one element.
+// Clicking waits for matching elements to reach the state given by wait_for.
+//
+// Each element is scrolled into view and clicked with real mouse events at its
+// center.  Before clicking, the element must be visible, enabled, and not covered
+// by another element; clicking waits (up to the timeout) for this to be the case.
 //
 // #### Examples
 //
@@ -42,6 +68,34 @@ type ClickArgs struct {
 //	}
 //
 // ```
+//
+// ##### Click a button once it has become visible and enabled.
+// ```
+//
+//	click "#checkout" {
+//	  wait_for: "enabled",
+//	  timeout:  "10s",
+//	}
+//
+// ```
+//
+// ##### Move the pointer to a button like a person would, then click it.
+// ```
+//
+//	click "#submit" {
+//	  path: true,
+//	}
+//
+// ```
+//
+// ##### Double-click on a table cell to edit it.
+// ```
+//
+//	click "td.price" {
+//	  count: 2,
+//	}
+//
+// ```
 func (self *Commands) Click(selector dom.Selector, args *ClickArgs) ([]*dom.Element, error) {
 	if args == nil {
 		args = &ClickArgs{}
@@ -49,30 +103,22 @@ func (self *Commands) Click(selector dom.Selector, args *ClickArgs) ([]*dom.Elem
 
 	defaults.SetDefaults(args)
 	args.Delay = utils.FudgeDuration(args.Delay)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
 
-	if elements, err := self.Select(selector, nil); err == nil {
-		if mt := args.MatchText; mt != `` {
-			if rx, err := regexp.Compile(mt); err == nil {
-				var matches = make([]*dom.Element, 0)
-
-				for _, el := range elements {
-					if rx.MatchString(el.Text) {
-						matches = append(matches, el)
-					}
+	if elements, err := self.WaitForElement(selector, &WaitForElementArgs{
+		State:     args.WaitFor,
+		MatchText: args.MatchText,
+		Timeout:   args.Timeout,
+	}); err == nil {
+		if len(elements) == 1 || args.Multiple {
+			for i, element := range elements {
+				if i > 0 && args.Delay > 0 {
+					time.Sleep(args.Delay)
 				}
 
-				elements = matches
-			} else {
-				return nil, fmt.Errorf("match_text: %v", err)
-			}
-		}
-
-		if len(elements) == 1 || args.Multiple {
-			if _, err := self.browser.Tab().Evaluate(fmt.Sprintf(
-				"document.querySelectorAll(%q).forEach(function(i){ i.click() })",
-				selector,
-			)); err != nil {
-				return nil, err
+				if err := self.clickElement(element, args); err != nil {
+					return nil, err
+				}
 			}
 
 			return elements, nil
@@ -84,15 +130,127 @@ func (self *Commands) Click(selector dom.Selector, args *ClickArgs) ([]*dom.Elem
 	}
 }
 
+// wait for the given element to become clickable, then click on it
+func (self *Commands) clickElement(element *dom.Element, args *ClickArgs) error {
+	tab := self.browser.Tab()
+
+	if args.Force {
+		_, err := tab.EvaluateOn(element, `this.click()`)
+		return err
+	}
+
+	path, err := pointerPath(args.Path)
+
+	if err != nil {
+		return err
+	}
+
+	if x, y, err := self.waitForClickablePoint(element, args.Timeout); err == nil {
+		return tab.ClickAt(x, y, &browser.MouseActionConfig{
+			Button: browser.Button(args.Button),
+			Count:  args.Count,
+			Path:   path,
+		})
+	} else {
+		return err
+	}
+}
+
+// wait for the given element to become visible, enabled, and unobscured, and return the
+// coordinates of its center
+func (self *Commands) waitForClickablePoint(element *dom.Element, timeout time.Duration) (float64, float64, error) {
+	started := time.Now()
+
+	for {
+		if x, y, err := self.browser.Tab().ClickablePoint(element); err == nil {
+			return x, y, nil
+		} else if !dom.IsNotActionableErr(err) || time.Since(started) > timeout {
+			return 0, 0, err
+		}
+
+		time.Sleep(actionabilityInterval)
+	}
+}
+
+// wait for the given selector to match exactly one element, and for that element to become
+// clickable; then return the element and the coordinates of its center
+func (self *Commands) waitForElementPoint(selector dom.Selector, timeout time.Duration) (*dom.Element, float64, float64, error) {
+	if elements, err := self.WaitForElement(selector, &WaitForElementArgs{
+		State:   `attached`,
+		Timeout: timeout,
+	}); err == nil {
+		if len(elements) != 1 {
+			return nil, 0, 0, dom.TooManyMatchesErr(selector, 1, len(elements))
+		}
+
+		if x, y, err := self.waitForClickablePoint(elements[0], timeout); err == nil {
+			return elements[0], x, y, nil
+		} else {
+			return nil, 0, 0, err
+		}
+	} else {
+		return nil, 0, 0, err
+	}
+}
+
 type ClickAtArgs struct {
 	// The X-coordinate to click at
 	X int `json:"x"`
 
 	// The Y-coordinate to click at
 	Y int `json:"y"`
+
+	// Which mouse button to click with; one of "left", "middle", or "right".
+	Button string `json:"button" default:"left"`
+
+	// How many times to click (e.g.: 2 for a double-click).
+	Count int `json:"count" default:"1"`
+
+	// Move the pointer to the given coordinates along a human-like path rather than jumping
+	// straight there (see the "path" option of mouse).
+	Path interface{} `json:"path"`
 }
 
-// Click the page at the given X, Y coordinates.
-func (self *Commands) ClickAt(args *ClickAtArgs) ([]dom.Element, error) {
-	return nil, browser.NotImplemented
+// Click the page at the given X, Y coordinates (relative to the viewport), and return the element
+// that was clicked on.
+//
+// #### Examples
+//
+// ##### Click near the top-left corner of the page.
+// ```
+//
+//	click_at {
+//	  x: 10,
+//	  y: 10,
+//	} -> $clicked
+//
+// ```
+func (self *Commands) ClickAt(args *ClickAtArgs) (*dom.Element, error) {
+	if args == nil {
+		args = &ClickAtArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	tab := self.browser.Tab()
+	x, y := float64(args.X), float64(args.Y)
+	path, err := pointerPath(args.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if element, err := tab.ElementAtPoint(x, y); err == nil {
+		if err := tab.ClickAt(x, y, &browser.MouseActionConfig{
+			Button: browser.Button(args.Button),
+			Count:  args.Count,
+			Path:   path,
+		}); err != nil {
+			return nil, err
+		}
+
+		return element, nil
+	} else {
+		return nil, err
+	}
 }
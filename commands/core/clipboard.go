@@ -0,0 +1,125 @@
+package core
+
+import (
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-webfriend/dom"
+	"github.com/ghetzel/go-webfriend/utils"
+)
+
+type ClipboardWriteArgs struct {
+	// The plain text to write to the clipboard.
+	Text string `json:"text"`
+
+	// HTML to write to the clipboard alongside the plain text.  Pages that accept rich text will
+	// paste this instead of the plain text.
+	HTML string `json:"html"`
+}
+
+type ClipboardContents struct {
+	// The plain text contents of the clipboard.
+	Text string `json:"text"`
+
+	// The HTML contents of the clipboard (if any).
+	HTML string `json:"html,omitempty"`
+}
+
+// Write text (and optionally HTML) to the clipboard.  The current page is granted permission to
+// access the clipboard as needed.
+//
+// #### Examples
+//
+// ##### Paste a large document into an editor.
+// ```
+// clipboard_write $document
+// paste '#editor'
+// ```
+//
+// ##### Write rich text to the clipboard.
+// ```
+//
+//	clipboard_write {
+//	  text: 'Hello world',
+//	  html: '<b>Hello</b> world',
+//	}
+//
+// ```
+func (self *Commands) ClipboardWrite(textOrArgs interface{}, args *ClipboardWriteArgs) error {
+	if args == nil {
+		args = &ClipboardWriteArgs{}
+	}
+
+	if text, err := valueOrArgs(textOrArgs, args); err != nil {
+		return err
+	} else if text != `` {
+		args.Text = text
+	}
+
+	defaults.SetDefaults(args)
+
+	return self.browser.Tab().WriteClipboard(args.Text, args.HTML)
+}
+
+// Read the contents of the clipboard.  The current page is granted permission to access the
+// clipboard as needed.
+//
+// #### Examples
+//
+// ##### Verify that a "copy to clipboard" button works.
+// ```
+// click '#copy-link'
+// clipboard_read -> $clipboard
+//
+//	if $clipboard.text != 'https://example.com/share/123' {
+//	  fail "Unexpected clipboard contents: {clipboard[text]}"
+//	}
+//
+// ```
+func (self *Commands) ClipboardRead() (*ClipboardContents, error) {
+	if text, html, err := self.browser.Tab().ReadClipboard(); err == nil {
+		return &ClipboardContents{
+			Text: text,
+			HTML: html,
+		}, nil
+	} else {
+		return nil, err
+	}
+}
+
+type PasteArgs struct {
+	// The timeout before we stop waiting for the element to appear.
+	Timeout time.Duration `json:"timeout" default:"5s"`
+}
+
+// Paste the contents of the clipboard into the element matching the given selector (or into the
+// focused element if no selector is given).  This fires a real "paste" event, just as if the user
+// had pressed Control+V.
+func (self *Commands) Paste(selector dom.Selector, args *PasteArgs) error {
+	if args == nil {
+		args = &PasteArgs{}
+	}
+
+	defaults.SetDefaults(args)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
+
+	tab := self.browser.Tab()
+
+	if !selector.IsNone() {
+		if elements, err := self.Select(selector, &SelectArgs{
+			Timeout: args.Timeout,
+		}); err == nil {
+			if len(elements) > 1 {
+				return dom.TooManyMatchesErr(selector, 1, len(elements))
+			}
+
+			if _, err := tab.EvaluateOn(elements[0], `this.focus()`); err != nil {
+				return err
+			}
+		} else {
+			return err
+		}
+	}
+
+	return tab.Paste()
+}
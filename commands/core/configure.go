@@ -5,6 +5,7 @@ import (
 
 	defaults "github.com/ghetzel/go-defaults"
 	"github.com/ghetzel/go-stockutil/colorutil"
+	"github.com/ghetzel/go-webfriend/browser"
 )
 
 type ConfigureArgs struct {
@@ -23,7 +24,7 @@ type ConfigureArgs struct {
 	// Disable JavaScript execution in the browser.
 	DisableScripts bool `json:"disable_scripts"`
 
-	// Emulate a touch-capable device.
+	// Emulate a touch-capable device.  This is required for touch gestures (tap, swipe, pinch).
 	EmulateTouch bool `json:"emulate_touch"`
 
 	// Set the default background color of the underlying window in the following formats: `#RRGGBB`, `#RRGGBBAA`, `rgb()`, `rgba()`, `hsv()`, `hsva()`, `hsl()`, `hsla()`.
@@ -75,9 +76,9 @@ func (self *Commands) Configure(args *ConfigureArgs) error {
 		`value`: args.DisableScripts,
 	})
 
-	self.browser.Tab().AsyncRPC(`Emulation`, `setTouchEmulationEnabled`, map[string]interface{}{
-		`value`: args.EmulateTouch,
-	})
+	if err := self.browser.Tab().SetTouchEmulation(args.EmulateTouch); err != nil {
+		return err
+	}
 
 	self.browser.Tab().AsyncRPC(`Emulation`, `setScrollbarsHidden`, map[string]interface{}{
 		`hidden`: args.HideScrollbars,
@@ -87,17 +88,19 @@ func (self *Commands) Configure(args *ConfigureArgs) error {
 		if col, err := colorutil.Parse(bgcolor); err == nil {
 			r, g, b, a := col.RGBA255()
 
-			self.browser.Tab().AsyncRPC(`Emulation`, `setDefaultBackgroundColorOverride`, map[string]interface{}{
-				`r`: r,
-				`g`: g,
-				`b`: b,
-				`a`: a,
-			})
+			if err := self.browser.Tab().SetBackgroundColor(&browser.HighlightColor{
+				R: int(r),
+				G: int(g),
+				B: int(b),
+				A: float64(a) / 255,
+			}); err != nil {
+				return err
+			}
 		} else {
 			return fmt.Errorf("invalid background color: %v", err)
 		}
-	} else {
-		self.browser.Tab().AsyncRPC(`Emulation`, `setDefaultBackgroundColorOverride`, nil)
+	} else if err := self.browser.Tab().SetBackgroundColor(nil); err != nil {
+		return err
 	}
 
 	return nil
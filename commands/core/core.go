@@ -3,7 +3,10 @@ package core
 
 import (
 	"github.com/ghetzel/friendscript/commands/core"
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/typeutil"
 	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/dom"
 )
 
 type Commands struct {
@@ -23,3 +26,22 @@ func New(browser *browser.Browser) *Commands {
 
 	return cmd
 }
+
+// Commands whose first argument is optional receive a lone options object (e.g.: `tap { x: 10 }`)
+// as their first argument.  This decodes such an object into args, or otherwise returns the first
+// argument as a selector.
+func selectorOrArgs(first interface{}, args interface{}) (dom.Selector, error) {
+	value, err := valueOrArgs(first, args)
+	return dom.Selector(value), err
+}
+
+// Like selectorOrArgs, but returns a first argument that isn't an options object as a string.
+func valueOrArgs(first interface{}, args interface{}) (string, error) {
+	if typeutil.IsMap(first) {
+		return ``, maputil.TaggedStructFromMap(maputil.M(first).MapNative(), args, `json`)
+	} else if first != nil {
+		return typeutil.String(first), nil
+	}
+
+	return ``, nil
+}
@@ -20,6 +20,12 @@ type FieldArgs struct {
 
 	// An element to click after the field value is changed.
 	Click dom.Selector `json:"click"`
+
+	// Wait for matching fields to reach this state before entering data (see wait_for_element).
+	WaitFor string `json:"wait_for" default:"attached"`
+
+	// The timeout before we stop waiting for matching fields.
+	Timeout time.Duration `json:"timeout" default:"5s"`
 }
 
 // Locate and enter data into a form input field.
@@ -46,7 +52,10 @@ func (self *Commands) Field(selector dom.Selector, args *FieldArgs) ([]*dom.Elem
 
 	defaults.SetDefaults(args)
 
-	if elements, err := self.Select(selector, nil); err == nil {
+	if elements, err := self.WaitForElement(selector, &WaitForElementArgs{
+		State:   args.WaitFor,
+		Timeout: args.Timeout,
+	}); err == nil {
 		for _, field := range elements {
 			if args.Autoclear {
 				if _, err := self.browser.Tab().EvaluateOn(field, `this.value = ''`); err != nil {
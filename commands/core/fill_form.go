@@ -0,0 +1,316 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/sliceutil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/dom"
+	"github.com/ghetzel/go-webfriend/utils"
+)
+
+// input types that are filled in by typing into them; all others have their value set directly
+var typeableInputTypes = []string{
+	``,
+	`email`,
+	`number`,
+	`password`,
+	`search`,
+	`tel`,
+	`text`,
+	`url`,
+}
+
+type FillFormArgs struct {
+	// A map of fields to fill in, keyed on each field's name, ID, label text, aria-label, or
+	// placeholder.  Values are entered according to the type of field: text is typed into inputs and
+	// textareas, <select> options are chosen by value or label, checkboxes and radio buttons are
+	// checked by value or label (or set to a boolean), and file inputs are given a path (or list
+	// of paths) to upload.
+	Fields map[string]interface{} `json:"fields"`
+
+	// Whether to clear the existing contents of text fields before entering new data.
+	Autoclear bool `json:"autoclear" default:"true"`
+
+	// Whether to submit the form after all fields have been filled in.
+	Submit bool `json:"submit"`
+
+	// Whether to return an error if any fields could not be found or filled in.
+	Strict bool `json:"strict"`
+
+	// The timeout before we stop waiting for the form to appear.
+	Timeout time.Duration `json:"timeout" default:"5s"`
+}
+
+type FillFormResponse struct {
+	// The fields that were successfully filled in.
+	Filled []string `json:"filled"`
+
+	// The fields that could not be found in the form.
+	Missing []string `json:"missing"`
+
+	// The fields that were found but could not be filled in, and why.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Fill in multiple fields of a form at once.  Each field is located within the form by its name,
+// ID, label text, aria-label, or placeholder, and is filled in using the interaction appropriate for
+// its type.  The names of fields that could not be found are returned rather than causing an error
+// (unless strict is set).
+//
+// #### Examples
+//
+// ##### Fill in and submit a signup form.
+// ```
+//
+//	fill_form 'form#signup' {
+//	  fields: {
+//	    'email':            'me@example.com',
+//	    'Password':         'p@ssw0rd!',
+//	    'country':          'Canada',
+//	    'plan':             'pro',
+//	    'Interests':        ['music', 'travel'],
+//	    'Send me updates':  false,
+//	    'avatar':           '~/Pictures/me.png',
+//	  },
+//	  submit: true,
+//	} -> $result
+//
+//	if $result.missing {
+//	  log "Could not find fields: {result[missing]}"
+//	}
+//
+// ```
+func (self *Commands) FillForm(selector dom.Selector, args *FillFormArgs) (*FillFormResponse, error) {
+	if args == nil {
+		args = &FillFormArgs{}
+	}
+
+	defaults.SetDefaults(args)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
+
+	var form *dom.Element
+
+	if elements, err := self.Select(selector, &SelectArgs{
+		Timeout: args.Timeout,
+	}); err == nil {
+		form = elements[0]
+	} else {
+		return nil, err
+	}
+
+	response := &FillFormResponse{
+		Filled:  make([]string, 0),
+		Missing: make([]string, 0),
+		Errors:  make(map[string]string),
+	}
+
+	tab := self.browser.Tab()
+	keys := maputil.StringKeys(args.Fields)
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if controls, err := tab.FormControls(form, key); err == nil {
+			if len(controls) == 0 {
+				response.Missing = append(response.Missing, key)
+			} else if err := self.fillControls(controls, args.Fields[key], args); err == nil {
+				response.Filled = append(response.Filled, key)
+			} else {
+				response.Errors[key] = err.Error()
+			}
+		} else {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+	}
+
+	if args.Strict {
+		if len(response.Missing) > 0 {
+			return response, fmt.Errorf("Could not find fields: %s", strings.Join(response.Missing, `, `))
+		}
+
+		for _, key := range keys {
+			if msg, ok := response.Errors[key]; ok {
+				return response, fmt.Errorf("%s: %s", key, msg)
+			}
+		}
+	}
+
+	if args.Submit {
+		if _, err := tab.EvaluateOn(form, `
+			var form = (this.tagName === 'FORM' ? this : this.closest('form'));
+
+			if (form) {
+				if (form.requestSubmit) {
+					form.requestSubmit();
+				} else {
+					form.submit();
+				}
+			}
+		`); err != nil {
+			return response, fmt.Errorf("submit: %v", err)
+		}
+	}
+
+	return response, nil
+}
+
+// fill in the given controls (all of which were matched by the same field key) with a value
+func (self *Commands) fillControls(controls []*dom.Element, value interface{}, args *FillFormArgs) error {
+	tab := self.browser.Tab()
+	checkables := 0
+
+	for _, control := range controls {
+		switch inputType(control) {
+		case `checkbox`, `radio`:
+			checkables += 1
+		}
+	}
+
+	// groups of checkboxes and radio buttons are filled in together
+	if checkables == len(controls) {
+		return self.fillCheckables(controls, value)
+	}
+
+	values := sliceutil.Stringify(sliceutil.Sliceify(value))
+
+	for _, control := range controls {
+		if !control.Enabled {
+			return fmt.Errorf("field is disabled")
+		}
+
+		switch control.Name {
+		case `select`:
+			if unmatched, err := tab.SelectOptions(control, values); err == nil {
+				if len(unmatched) > 0 {
+					return fmt.Errorf("no options matched %s", strings.Join(unmatched, `, `))
+				}
+			} else {
+				return err
+			}
+
+		case `input`:
+			switch itype := inputType(control); itype {
+			case `file`:
+				if err := tab.SetFileInputFiles(control, values); err != nil {
+					return err
+				}
+
+			case `checkbox`, `radio`:
+				if err := self.fillCheckables([]*dom.Element{control}, value); err != nil {
+					return err
+				}
+
+			default:
+				if sliceutil.ContainsString(typeableInputTypes, itype) {
+					if err := self.typeInto(control, `this.value = ''`, value, args); err != nil {
+						return err
+					}
+				} else if err := tab.SetInputValue(control, value); err != nil {
+					return err
+				}
+			}
+
+		case `textarea`:
+			if err := self.typeInto(control, `this.value = ''`, value, args); err != nil {
+				return err
+			}
+
+		default:
+			if err := self.typeInto(control, `this.innerText = ''`, value, args); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// focus the given control and type the value into it, optionally clearing it first
+func (self *Commands) typeInto(control *dom.Element, clear string, value interface{}, args *FillFormArgs) error {
+	tab := self.browser.Tab()
+
+	if args.Autoclear {
+		if _, err := tab.EvaluateOn(control, clear); err != nil {
+			return fmt.Errorf("autoclear: %v", err)
+		}
+	}
+
+	if _, err := tab.EvaluateOn(control, `this.focus()`); err != nil {
+		return fmt.Errorf("focus: %v", err)
+	}
+
+	if _, err := self.Type(value, nil); err != nil {
+		return fmt.Errorf("type: %v", err)
+	}
+
+	return nil
+}
+
+// check the checkboxes and radio buttons whose value or label matches the given value(s), and
+// uncheck any other checkboxes.  A boolean value checks or unchecks a lone checkbox.
+func (self *Commands) fillCheckables(controls []*dom.Element, value interface{}) error {
+	tab := self.browser.Tab()
+
+	if checked, ok := value.(bool); ok {
+		if len(controls) > 1 {
+			return fmt.Errorf("a group of %d options requires the value(s) to select, not %v", len(controls), checked)
+		}
+
+		return tab.SetChecked(controls[0], checked)
+	}
+
+	values := sliceutil.Stringify(sliceutil.Sliceify(value))
+	matched := make(map[string]bool)
+	radioChecked := false
+
+	for _, control := range controls {
+		var isMatch bool
+
+		if label, err := tab.ControlLabel(control); err == nil {
+			for _, v := range values {
+				if v == typeutil.String(control.Attributes[`value`]) || strings.EqualFold(v, label) {
+					matched[v] = true
+					isMatch = true
+				}
+			}
+		} else {
+			return err
+		}
+
+		switch inputType(control) {
+		case `radio`:
+			if isMatch && !radioChecked {
+				if err := tab.SetChecked(control, true); err != nil {
+					return err
+				}
+
+				radioChecked = true
+			}
+		default:
+			if err := tab.SetChecked(control, isMatch); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, v := range values {
+		if !matched[v] {
+			return fmt.Errorf("no options matched %s", v)
+		}
+	}
+
+	return nil
+}
+
+func inputType(control *dom.Element) string {
+	if control.Name == `input` {
+		return strings.ToLower(typeutil.String(control.Attributes[`type`]))
+	}
+
+	return ``
+}
@@ -1,18 +1,13 @@
 package core
 
 import (
-	"fmt"
-
 	"github.com/ghetzel/go-webfriend/dom"
 )
 
 // Focuses the given HTML element described by selector. One and only one element may match the selector.
 func (self *Commands) Focus(selector dom.Selector) (*dom.Element, error) {
 	if elements, err := self.Select(selector, nil); err == nil && len(elements) == 1 {
-		if _, err := self.browser.Tab().Evaluate(fmt.Sprintf(
-			"document.querySelector(%q).focus()",
-			selector,
-		)); err == nil {
+		if _, err := self.browser.Tab().EvaluateOn(elements[0], `this.focus()`); err == nil {
 			return elements[0], nil
 		} else {
 			return nil, err
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/dom"
+	"github.com/ghetzel/go-webfriend/utils"
+	"github.com/gobwas/glob"
+)
+
+type Frame struct {
+	// The unique ID of the frame.
+	ID string `json:"id"`
+
+	// The ID of the frame containing this one (empty for the main frame).
+	Parent string `json:"parent,omitempty"`
+
+	// The name of the frame (from the "name" attribute of its <iframe>).
+	Name string `json:"name,omitempty"`
+
+	// The URL of the document loaded in the frame.
+	URL string `json:"url"`
+
+	// The security origin of the document loaded in the frame.
+	Origin string `json:"origin"`
+
+	// Whether commands are currently operating inside of this frame.
+	Active bool `json:"active"`
+}
+
+func frameFromBrowser(frame *browser.Frame, active *browser.Frame) *Frame {
+	out := &Frame{
+		ID:     frame.ID,
+		Parent: frame.ParentID,
+		Name:   frame.Name,
+		URL:    frame.URL,
+		Origin: frame.SecurityOrigin,
+	}
+
+	if active == nil {
+		out.Active = frame.IsMain()
+	} else {
+		out.Active = (frame.ID == active.ID)
+	}
+
+	return out
+}
+
+// List all frames in the current page, starting with the main frame.
+func (self *Commands) Frames() ([]*Frame, error) {
+	tab := self.browser.Tab()
+
+	if frames, err := tab.Frames(); err == nil {
+		out := make([]*Frame, 0)
+
+		for _, frame := range frames {
+			out = append(out, frameFromBrowser(frame, tab.Frame()))
+		}
+
+		return out, nil
+	} else {
+		return nil, err
+	}
+}
+
+type SwitchFrameArgs struct {
+	// Switch to the frame with this name.
+	Name string `json:"name"`
+
+	// Switch to the first frame whose URL matches this pattern (e.g.: "https://*.stripe.com/*").
+	URL string `json:"url"`
+
+	// The timeout before we stop waiting for a matching frame to appear.
+	Timeout time.Duration `json:"timeout" default:"10s"`
+
+	// The polling interval between frame re-checks.
+	Interval time.Duration `json:"interval" default:"125ms"`
+}
+
+// Make subsequent commands (click, field, javascript, etc.) operate inside of a frame.  The frame
+// can be identified by a selector matching its <iframe> element, or by its name or URL.  Frames
+// from other origins are supported.  Specifying a selector of "none" switches back to the main
+// frame, which also happens whenever the page navigates.
+//
+// #### Examples
+//
+// ##### Fill in a card number in an embedded payment form, then return to the page.
+// ```
+// switch_frame 'iframe#payment'
+// field '#card-number' { value: '4242424242424242' }
+// switch_frame none
+// ```
+//
+// ##### Switch to a frame by its URL.
+// ```
+//
+//	switch_frame {
+//	  url: 'https://login.example.com/*',
+//	} -> $frame
+//
+// log "Now operating in {frame[url]}"
+// ```
+func (self *Commands) SwitchFrame(frameOrArgs interface{}, args *SwitchFrameArgs) (*Frame, error) {
+	if args == nil {
+		args = &SwitchFrameArgs{}
+	}
+
+	selector, err := selectorOrArgs(frameOrArgs, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defaults.SetDefaults(args)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
+	args.Interval = utils.FudgeDuration(args.Interval)
+
+	tab := self.browser.Tab()
+
+	if selector.IsNone() && args.Name == `` && args.URL == `` {
+		tab.SwitchFrame(nil)
+		return nil, nil
+	}
+
+	if !selector.IsNone() {
+		if elements, err := self.Select(selector, &SelectArgs{
+			Timeout: args.Timeout,
+		}); err == nil && len(elements) == 1 {
+			if frame, err := tab.FrameForElement(elements[0]); err == nil {
+				tab.SwitchFrame(frame)
+				return frameFromBrowser(frame, frame), nil
+			} else {
+				return nil, err
+			}
+		} else if l := len(elements); l > 1 {
+			return nil, dom.TooManyMatchesErr(selector, 1, l)
+		} else {
+			return nil, err
+		}
+	}
+
+	var urlPattern glob.Glob
+
+	if args.URL != `` {
+		if pattern, err := glob.Compile(args.URL); err == nil {
+			urlPattern = pattern
+		} else {
+			return nil, fmt.Errorf("url: %v", err)
+		}
+	}
+
+	started := time.Now()
+
+	for time.Since(started) <= args.Timeout {
+		if frames, err := tab.Frames(); err == nil {
+			for _, frame := range frames {
+				if args.Name != `` && frame.Name != args.Name {
+					continue
+				} else if urlPattern != nil && !urlPattern.Match(frame.URL) {
+					continue
+				}
+
+				tab.SwitchFrame(frame)
+				return frameFromBrowser(frame, frame), nil
+			}
+		} else {
+			return nil, err
+		}
+
+		time.Sleep(args.Interval)
+	}
+
+	return nil, fmt.Errorf("Timed out waiting for a matching frame")
+}
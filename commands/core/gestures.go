@@ -0,0 +1,344 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/dom"
+	"github.com/ghetzel/go-webfriend/utils"
+)
+
+type HoverArgs struct {
+	// The timeout before we stop waiting for the element to become visible and unobscured.
+	Timeout time.Duration `json:"timeout" default:"5s"`
+
+	// Move the pointer to the element along a human-like path rather than jumping straight there
+	// (see the "path" option of mouse).
+	Path interface{} `json:"path"`
+}
+
+// Move the mouse over the element matching the given selector.  The element is scrolled into view,
+// and must be visible and not covered by another element.
+//
+// #### Examples
+//
+// ##### Open a dropdown menu that appears on hover, then click an item in it.
+// ```
+// hover 'nav .account'
+// click 'nav .account-menu a.settings'
+// ```
+func (self *Commands) Hover(selector dom.Selector, args *HoverArgs) (*dom.Element, error) {
+	if args == nil {
+		args = &HoverArgs{}
+	}
+
+	defaults.SetDefaults(args)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
+
+	path, err := pointerPath(args.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if element, x, y, err := self.waitForElementPoint(selector, args.Timeout); err == nil {
+		return element, self.browser.Tab().MoveMouse(x, y, &browser.MouseActionConfig{
+			Action: browser.Moved,
+			Path:   path,
+		})
+	} else {
+		return nil, err
+	}
+}
+
+type DragArgs struct {
+	// The element to drop onto.
+	To dom.Selector `json:"to"`
+
+	// If no element is being dragged, the X-coordinate to start dragging from.
+	FromX *float64 `json:"from_x"`
+
+	// If no element is being dragged, the Y-coordinate to start dragging from.
+	FromY *float64 `json:"from_y"`
+
+	// If no element to drop onto is given, the X-coordinate to drop at.
+	ToX *float64 `json:"to_x"`
+
+	// If no element to drop onto is given, the Y-coordinate to drop at.
+	ToY *float64 `json:"to_y"`
+
+	// The number of intermediate mouse movements to make between the start and end points.
+	Steps int `json:"steps" default:"10"`
+
+	// How long the drag should take.
+	Duration time.Duration `json:"duration" default:"250ms"`
+
+	// The timeout before we stop waiting for the elements to become visible and unobscured.
+	Timeout time.Duration `json:"timeout" default:"5s"`
+
+	// Move the pointer along human-like paths rather than in straight lines (see the "path" option
+	// of mouse).  When given, the path's duration and steps are used for the drag itself.
+	Path interface{} `json:"path"`
+}
+
+// Drag an element (or from a point) and drop it onto another element (or at a point).  Both
+// mouse-driven dragging (e.g.: sliders, maps) and HTML5 drag and drop (e.g.: elements with the
+// "draggable" attribute) are supported.
+//
+// #### Examples
+//
+// ##### Move a card to another column of a Kanban board.
+// ```
+//
+//	drag '#card-42' {
+//	  to: '#column-done',
+//	}
+//
+// ```
+//
+// ##### Pan a map by dragging it 200 pixels to the left.
+// ```
+//
+//	drag {
+//	  from_x: 400,
+//	  from_y: 300,
+//	  to_x:   200,
+//	  to_y:   300,
+//	}
+//
+// ```
+func (self *Commands) Drag(fromOrArgs interface{}, args *DragArgs) error {
+	if args == nil {
+		args = &DragArgs{}
+	}
+
+	from, err := selectorOrArgs(fromOrArgs, args)
+
+	if err != nil {
+		return err
+	}
+
+	defaults.SetDefaults(args)
+	args.Duration = utils.FudgeDuration(args.Duration)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
+
+	path, err := pointerPath(args.Path)
+
+	if err != nil {
+		return err
+	}
+
+	var fromX, fromY, toX, toY float64
+
+	if !from.IsNone() {
+		if _, x, y, err := self.waitForElementPoint(from, args.Timeout); err == nil {
+			fromX, fromY = x, y
+		} else {
+			return fmt.Errorf("from: %v", err)
+		}
+	} else if args.FromX != nil && args.FromY != nil {
+		fromX, fromY = *args.FromX, *args.FromY
+	} else {
+		return fmt.Errorf("Must specify an element to drag, or both from_x and from_y")
+	}
+
+	if !args.To.IsNone() {
+		if _, x, y, err := self.waitForElementPoint(args.To, args.Timeout); err == nil {
+			toX, toY = x, y
+		} else {
+			return fmt.Errorf("to: %v", err)
+		}
+	} else if args.ToX != nil && args.ToY != nil {
+		toX, toY = *args.ToX, *args.ToY
+	} else {
+		return fmt.Errorf("Must specify an element to drop onto (to), or both to_x and to_y")
+	}
+
+	return self.browser.Tab().Drag(fromX, fromY, toX, toY, &browser.GestureConfig{
+		Steps:    args.Steps,
+		Duration: args.Duration,
+		Path:     path,
+	})
+}
+
+type TapArgs struct {
+	// If no element is given, the X-coordinate to tap at.
+	X float64 `json:"x"`
+
+	// If no element is given, the Y-coordinate to tap at.
+	Y float64 `json:"y"`
+
+	// The timeout before we stop waiting for the element to become visible and unobscured.
+	Timeout time.Duration `json:"timeout" default:"5s"`
+}
+
+// Tap on the element matching the given selector (or at a point) with a touch gesture.  Touch
+// emulation must be enabled first (see configure).
+//
+// #### Examples
+//
+// ##### Tap on a button on a mobile site.
+// ```
+// configure { emulate_touch: true }
+// tap '#menu-toggle'
+// ```
+func (self *Commands) Tap(targetOrArgs interface{}, args *TapArgs) error {
+	if args == nil {
+		args = &TapArgs{}
+	}
+
+	if x, y, err := self.gestureTarget(targetOrArgs, args, &args.X, &args.Y, &args.Timeout); err == nil {
+		return self.browser.Tab().Tap(x, y)
+	} else {
+		return err
+	}
+}
+
+type SwipeArgs struct {
+	// If no element is given, the X-coordinate to start swiping from.
+	X float64 `json:"x"`
+
+	// If no element is given, the Y-coordinate to start swiping from.
+	Y float64 `json:"y"`
+
+	// The direction to swipe in; one of "left", "right", "up", or "down".
+	Direction string `json:"direction" default:"left"`
+
+	// How far to swipe (in pixels).
+	Distance float64 `json:"distance" default:"200"`
+
+	// The number of intermediate touch movements to make.
+	Steps int `json:"steps" default:"10"`
+
+	// How long the swipe should take.
+	Duration time.Duration `json:"duration" default:"250ms"`
+
+	// The timeout before we stop waiting for the element to become visible and unobscured.
+	Timeout time.Duration `json:"timeout" default:"5s"`
+}
+
+// Swipe across the element matching the given selector (or from a point) with a touch gesture.
+// The swipe starts at the center of the element.  Touch emulation must be enabled first (see
+// configure).
+//
+// #### Examples
+//
+// ##### Advance an image carousel to the next slide.
+// ```
+// configure { emulate_touch: true }
+//
+//	swipe '.carousel' {
+//	  direction: 'left',
+//	  distance:  300,
+//	}
+//
+// ```
+func (self *Commands) Swipe(targetOrArgs interface{}, args *SwipeArgs) error {
+	if args == nil {
+		args = &SwipeArgs{}
+	}
+
+	if x, y, err := self.gestureTarget(targetOrArgs, args, &args.X, &args.Y, &args.Timeout); err == nil {
+		toX, toY := x, y
+
+		switch args.Direction {
+		case `left`:
+			toX -= args.Distance
+		case `right`:
+			toX += args.Distance
+		case `up`:
+			toY -= args.Distance
+		case `down`:
+			toY += args.Distance
+		default:
+			return fmt.Errorf("Unsupported swipe direction %q", args.Direction)
+		}
+
+		return self.browser.Tab().Swipe(x, y, toX, toY, &browser.GestureConfig{
+			Steps:    args.Steps,
+			Duration: utils.FudgeDuration(args.Duration),
+		})
+	} else {
+		return err
+	}
+}
+
+type PinchArgs struct {
+	// If no element is given, the X-coordinate to center the pinch on.
+	X float64 `json:"x"`
+
+	// If no element is given, the Y-coordinate to center the pinch on.
+	Y float64 `json:"y"`
+
+	// How much to zoom by; values greater than 1 spread the fingers apart (zooming in), and values
+	// less than 1 bring them together (zooming out).
+	Scale float64 `json:"scale" default:"2"`
+
+	// How far apart the fingers start (in pixels).
+	Distance float64 `json:"distance" default:"100"`
+
+	// The number of intermediate touch movements to make.
+	Steps int `json:"steps" default:"10"`
+
+	// How long the pinch should take.
+	Duration time.Duration `json:"duration" default:"250ms"`
+
+	// The timeout before we stop waiting for the element to become visible and unobscured.
+	Timeout time.Duration `json:"timeout" default:"5s"`
+}
+
+// Perform a two-finger pinch gesture centered on the element matching the given selector (or on a
+// point).  Touch emulation must be enabled first (see configure).
+//
+// #### Examples
+//
+// ##### Zoom out of a map.
+// ```
+// configure { emulate_touch: true }
+//
+//	pinch '#map' {
+//	  scale: 0.5,
+//	}
+//
+// ```
+func (self *Commands) Pinch(targetOrArgs interface{}, args *PinchArgs) error {
+	if args == nil {
+		args = &PinchArgs{}
+	}
+
+	if x, y, err := self.gestureTarget(targetOrArgs, args, &args.X, &args.Y, &args.Timeout); err == nil {
+		return self.browser.Tab().Pinch(x, y, args.Distance, args.Scale, &browser.GestureConfig{
+			Steps:    args.Steps,
+			Duration: utils.FudgeDuration(args.Duration),
+		})
+	} else {
+		return err
+	}
+}
+
+// decode the arguments of a touch gesture command, and return the point the gesture should be
+// performed at: either the center of the element matching the target selector, or the given point
+func (self *Commands) gestureTarget(targetOrArgs interface{}, args interface{}, x *float64, y *float64, timeout *time.Duration) (float64, float64, error) {
+	target, err := selectorOrArgs(targetOrArgs, args)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defaults.SetDefaults(args)
+	*timeout = utils.FudgeDuration(*timeout)
+
+	if !self.browser.Tab().TouchEmulation() {
+		return 0, 0, fmt.Errorf("Touch emulation is not enabled; use 'configure { emulate_touch: true }' first")
+	}
+
+	if target.IsNone() {
+		return *x, *y, nil
+	} else if _, ex, ey, err := self.waitForElementPoint(target, *timeout); err == nil {
+		return ex, ey, nil
+	} else {
+		return 0, 0, err
+	}
+}
@@ -67,6 +67,12 @@ type GoArgs struct {
 
 	// Only provide credentials if the HTTP Basic Authentication Realm matches this one.
 	Realm string `json:"realm"`
+
+	// Provide a username if one is requested by the proxy server the current tab is using.
+	ProxyUsername string `json:"proxy_username"`
+
+	// Provide a password if one is requested by the proxy server the current tab is using.
+	ProxyPassword string `json:"proxy_password"`
 }
 
 type GoResponse struct {
@@ -90,6 +96,12 @@ type GoResponse struct {
 
 	// The protocol that was negotiated and used to load the page.
 	Protocol string `json:"protocol"`
+
+	// The security state of the loaded page (e.g.: "secure", "insecure", "neutral").
+	SecurityState string `json:"securityState,omitempty"`
+
+	// Details about the TLS connection and certificate used to load the page (if any).
+	Certificate *Certificate `json:"certificate,omitempty"`
 }
 
 // Navigate to a URL.
@@ -155,6 +167,11 @@ func (self *Commands) Go(uri string, args *GoArgs) (*GoResponse, error) {
 				if err := self.browser.Tab().AddNetworkIntercept(``, true, func(tab *browser.Tab, pattern *browser.NetworkRequestPattern, event *browser.Event) *browser.NetworkInterceptResponse {
 					response := &browser.NetworkInterceptResponse{}
 
+					// proxy challenges are answered using the proxy credentials (if any)
+					if event.P().String(`authChallenge.source`) == `Proxy` {
+						return nil
+					}
+
 					if event.P().Bool(`isNavigationRequest`) {
 						if origin := event.P().String(`authChallenge.origin`); origin != `` {
 							if args.Realm == `` || args.Realm == event.P().String(`authChallenge.realm`) {
@@ -179,6 +196,13 @@ func (self *Commands) Go(uri string, args *GoArgs) (*GoResponse, error) {
 				}
 			}
 
+			if args.ProxyUsername != `` || args.ProxyPassword != `` {
+				if err := self.browser.Tab().SetProxyCredentials(args.ProxyUsername, args.ProxyPassword); err != nil {
+					log.Warning(err)
+					return nil, fmt.Errorf("Failed to setup proxy authentication intercept")
+				}
+			}
+
 			if rv, err := self.browser.Tab().Navigate(u.String()); err == nil {
 				if args.WaitForLoad && args.Timeout > 0 {
 					// wait for the first event matching the given pattern
@@ -234,6 +258,10 @@ func (self *Commands) Go(uri string, args *GoArgs) (*GoResponse, error) {
 							cmdresp.URL = netreq.R().String(`response.url`)
 							cmdresp.MimeType = netreq.R().String(`response.mimeType`)
 							cmdresp.Protocol = netreq.R().String(`response.protocol`)
+							cmdresp.SecurityState = netreq.R().String(`response.securityState`)
+							cmdresp.Certificate = certificateFromSecurityDetails(
+								maputil.M(netreq.R().Get(`response.securityDetails`)),
+							)
 							cmdresp.RemoteAddress = fmt.Sprintf(
 								"%v:%v",
 								netreq.R().String(`response.remoteIPAddress`),
@@ -1,44 +1,87 @@
 package core
 
 import (
+	"fmt"
+	"time"
+
 	defaults "github.com/ghetzel/go-defaults"
 	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/utils"
 )
 
 type KeyArgs struct {
-	// The keyboard action to take; either "press" or "release"
+	// The keyboard action to take; one of "press" (press and release the key), "down" (press and
+	// hold the key until it is released), or "release"/"up" (release a held key).
 	Action  string `json:"action" default:"press"`
 	Alt     bool   `json:"alt,omitempty"`
 	Control bool   `json:"control,omitempty"`
 	Meta    bool   `json:"meta,omitempty"`
 	Shift   bool   `json:"shift,omitempty"`
 
-	// The numeric decimal keycode to send.
+	// The numeric decimal keycode to send (by default, this is determined by the key).
 	KeyCode int `json:"keycode,omitempty"`
+
+	// For "press" actions, how long the key(s) should be held down for.
+	HoldFor time.Duration `json:"hold_for" default:"30ms"`
 }
 
+// Press, hold, or release a key or key combination.  Keys are named as described at
+// https://developer.mozilla.org/en-US/docs/Web/API/KeyboardEvent/key/Key_Values (e.g.: "Enter",
+// "ArrowDown", "a"), or by their physical key code (e.g.: "KeyA", "Digit1").  Combinations are given
+// by joining key names with a "+" (e.g.: "Control+Shift+K").  Modifier keys that are held down
+// using the "down" action remain held for subsequent keyboard and mouse commands until released.
+//
+// #### Examples
+//
+// ##### Select all text in the focused element.
+// ```
+// key 'Control+a'
+// ```
+//
+// ##### Shift-click on several items in a list.
+// ```
+// key 'Shift' { action: 'down' }
+// click '#item-1'
+// click '#item-5'
+// key 'Shift' { action: 'up' }
+// ```
 func (self *Commands) Key(domKeyName string, args *KeyArgs) error {
 	if args == nil {
 		args = &KeyArgs{}
 	}
 
 	defaults.SetDefaults(args)
+	args.HoldFor = utils.FudgeDuration(args.HoldFor)
 
-	var action browser.KeyboardAction
+	tab := self.browser.Tab()
+	config := &browser.KeyboardActionConfig{
+		Alt:     args.Alt,
+		Control: args.Control,
+		Meta:    args.Meta,
+		Shift:   args.Shift,
+		KeyCode: args.KeyCode,
+	}
 
 	switch args.Action {
 	case `press`:
-		action = browser.KeyPressed
+		return tab.PressKeys(domKeyName, args.HoldFor, config)
+	case `down`, `hold`:
+		for _, key := range browser.ParseKeyChord(domKeyName) {
+			if err := tab.KeyDown(key, config); err != nil {
+				return err
+			}
+		}
+	case `up`, `release`:
+		keys := browser.ParseKeyChord(domKeyName)
+
+		for i := len(keys) - 1; i >= 0; i-- {
+			if err := tab.KeyUp(keys[i], config); err != nil {
+				return err
+			}
+		}
 	default:
-		action = browser.KeyReleased
+		return fmt.Errorf("Unsupported key action %q", args.Action)
 	}
 
-	return self.browser.Tab().SendKey(domKeyName, &browser.KeyboardActionConfig{
-		Action:  action,
-		Alt:     args.Alt,
-		Control: args.Control,
-		Meta:    args.Meta,
-		Shift:   args.Shift,
-		KeyCode: args.KeyCode,
-	})
+	return nil
 }
@@ -1,8 +1,14 @@
 package core
 
 import (
+	"fmt"
+	"reflect"
+
 	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/typeutil"
 	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/utils"
 )
 
 type MouseArgs struct {
@@ -38,8 +44,33 @@ type MouseArgs struct {
 
 	// How many clicks to issue if action is "press"
 	Count int `json:"count,omitempty"`
+
+	// Move the pointer to the given coordinates along a human-like path rather than jumping
+	// straight there.  Either true (for the default path), or an object with any of the following:
+	// "duration" (how long the movement takes, default 400ms), "steps" (the number of intermediate
+	// movements, default 25), "jitter" (the maximum random deviation of each movement in pixels,
+	// default 1.5), and "curvature" (how far the path may bow away from a straight line, as a
+	// fraction of the distance travelled, default 0.25).
+	Path interface{} `json:"path,omitempty"`
 }
 
+// Perform a low-level mouse action at the given coordinates (relative to the viewport).
+//
+// #### Examples
+//
+// ##### Move the pointer across a canvas like a person would.
+// ```
+//
+//	mouse {
+//	  x:    400,
+//	  y:    250,
+//	  path: {
+//	    duration: '800ms',
+//	    jitter:   3,
+//	  },
+//	}
+//
+// ```
 func (self *Commands) Mouse(args *MouseArgs) error {
 	if args == nil {
 		args = &MouseArgs{}
@@ -62,6 +93,19 @@ func (self *Commands) Mouse(args *MouseArgs) error {
 
 	// log.Noticef("M: %v %v %v (%v,%v)", args.Action, args.X, args.Y, args.WheelX, args.WheelY)
 
+	path, err := pointerPath(args.Path)
+
+	if err != nil {
+		return err
+	}
+
+	// travel to where a press or release will happen first
+	if path != nil && action != browser.Moved && action != browser.Scrolled {
+		if err := self.browser.Tab().MoveMouseAlong(args.X, args.Y, path, nil); err != nil {
+			return err
+		}
+	}
+
 	return self.browser.Tab().MoveMouse(args.X, args.Y, &browser.MouseActionConfig{
 		Action:  action,
 		Button:  browser.Button(args.Button),
@@ -72,5 +116,47 @@ func (self *Commands) Mouse(args *MouseArgs) error {
 		WheelX:  args.WheelX,
 		WheelY:  args.WheelY,
 		Count:   args.Count,
+		Path:    path,
 	})
 }
+
+// decode the "path" option of pointer commands into a pointer path (or nil if the pointer should
+// move directly to its destination)
+func pointerPath(value interface{}) (*browser.PointerPath, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	path := &browser.PointerPath{}
+	defaults.SetDefaults(path)
+
+	if typeutil.IsMap(value) {
+		pathM := maputil.M(value)
+
+		if v := pathM.Get(`duration`); !v.IsNil() {
+			path.Duration = utils.FudgeDuration(v.Duration())
+		}
+
+		if v := pathM.Get(`steps`); !v.IsNil() {
+			path.Steps = int(v.Int())
+		}
+
+		if v := pathM.Get(`jitter`); !v.IsNil() {
+			path.Jitter = v.Float()
+		}
+
+		if v := pathM.Get(`curvature`); !v.IsNil() {
+			path.Curvature = v.Float()
+		}
+
+		return path, nil
+	} else if typeutil.IsKind(value, reflect.Bool) {
+		if typeutil.Bool(value) {
+			return path, nil
+		}
+
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("path: expected true or an object, got %T", value)
+}
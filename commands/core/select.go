@@ -18,11 +18,32 @@ type SelectArgs struct {
 
 	// The polling interval between element re-checks.
 	Interval time.Duration `json:"interval" default:"125ms"`
+
+	// A list of computed CSS properties (e.g.: "color", "font-size") to retrieve for each matching element.
+	Styles []string `json:"styles"`
 }
 
 // Polls the DOM for an element that matches the given selector. Either the
 // element will be found and returned within the given timeout, or a
-// TimeoutError will be returned.
+// TimeoutError will be returned.  Each element is returned along with its
+// bounding box, visibility, enabled state, form value, and CSS path.
+//
+// #### Examples
+//
+// ##### Check whether a submit button is usable, and what color it is.
+// ```
+//
+//	select 'button[type="submit"]' {
+//	  styles: ['color', 'background-color'],
+//	} -> $buttons
+//
+//	loop $button in $buttons {
+//	  if $button.visible && $button.enabled {
+//	    log "Button color is {button[styles][color]}"
+//	  }
+//	}
+//
+// ```
 func (self *Commands) Select(selector dom.Selector, args *SelectArgs) ([]*dom.Element, error) {
 	if args == nil {
 		args = &SelectArgs{}
@@ -37,6 +58,12 @@ func (self *Commands) Select(selector dom.Selector, args *SelectArgs) ([]*dom.El
 	for time.Since(started) <= args.Timeout {
 		if elements, err := self.browser.Tab().ElementQuery(selector, nil); err == nil {
 			if len(elements) >= args.MinMatches {
+				if len(args.Styles) > 0 {
+					if err := self.browser.Tab().DescribeElements(elements, args.Styles...); err != nil {
+						return nil, err
+					}
+				}
+
 				return elements, nil
 			}
 		}
@@ -1,8 +1,6 @@
 package core
 
 import (
-	"fmt"
-
 	defaults "github.com/ghetzel/go-defaults"
 	"github.com/ghetzel/go-stockutil/maputil"
 	"github.com/ghetzel/go-stockutil/typeutil"
@@ -19,24 +17,104 @@ type NewTabArgs struct {
 	// Whether to automatically switch to the newly-created tab as the active
 	// tab for subsequent commands.
 	Autoswitch bool `json:"autoswitch" default:"true"`
+
+	// Whether the tab should be created in its own browser context, with cookies, cache, and
+	// storage that are not shared with other tabs.  This is implied if proxy is set.
+	Isolated bool `json:"isolated"`
+
+	// Route all requests made by the new tab through this proxy server (e.g.: "http://proxy:3128" or "socks5://proxy:1080").
+	Proxy string `json:"proxy"`
+
+	// A list of hosts that should not be routed through the proxy.
+	ProxyBypassList []string `json:"proxy_bypass_list"`
+
+	// Provide a username if one is requested by the proxy server.
+	ProxyUsername string `json:"proxy_username"`
+
+	// Provide a password if one is requested by the proxy server.
+	ProxyPassword string `json:"proxy_password"`
 }
 
-// [SKIP]
-// Open a new tab and navigate to the given URL.
+// Open a new tab and navigate to the given URL.  Tabs may be given their own proxy server,
+// which allows a single script to load pages from several different egress points.
+//
+// #### Examples
+//
+// ##### Compare a page as seen through two different proxies.
+// ```
+//
+//	new_tab "https://example.com" {
+//	  proxy: 'http://us.proxy.example.net:3128',
+//	} -> $us
+//
+// page::text -> $us_text
+//
+//	new_tab "https://example.com" {
+//	  proxy:          'http://eu.proxy.example.net:3128',
+//	  proxy_username: 'scraper',
+//	  proxy_password: 's3cr3t',
+//	} -> $eu
+//
+// page::text -> $eu_text
+// switch_tab $us
+// ```
 func (self *Commands) NewTab(url string, args *NewTabArgs) (browser.TabID, error) {
-	return ``, fmt.Errorf(`NI`)
+	if args == nil {
+		args = &NewTabArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	options := &browser.NewTabOptions{
+		Width:    args.Width,
+		Height:   args.Height,
+		Isolated: args.Isolated,
+	}
+
+	if args.Proxy != `` {
+		options.Proxy = &browser.ProxyConfig{
+			Server:     args.Proxy,
+			BypassList: args.ProxyBypassList,
+			Username:   args.ProxyUsername,
+			Password:   args.ProxyPassword,
+		}
+	}
+
+	if tab, err := self.browser.NewTab(options); err == nil {
+		if args.Autoswitch {
+			if _, err := self.browser.SwitchTab(tab.ID()); err != nil {
+				return ``, err
+			}
+		}
+
+		if url != `` {
+			if args.Autoswitch {
+				if _, err := self.Go(url, nil); err != nil {
+					return ``, err
+				}
+			} else if _, err := tab.Navigate(url); err != nil {
+				return ``, err
+			}
+		}
+
+		return browser.TabID(tab.ID()), nil
+	} else {
+		return ``, err
+	}
 }
 
-// [SKIP]
 // Close the tab identified by the given ID.
 func (self *Commands) CloseTab(id browser.TabID) error {
-	return fmt.Errorf(`NI`)
+	return self.browser.CloseTab(string(id))
 }
 
-// [SKIP]
 // Switches the active tab to a given tab.
-func (self *Commands) SwitchTab(id browser.TabID) (*browser.Tab, error) {
-	return nil, fmt.Errorf(`NI`)
+func (self *Commands) SwitchTab(id browser.TabID) (browser.TabID, error) {
+	if tab, err := self.browser.SwitchTab(string(id)); err == nil {
+		return browser.TabID(tab.ID()), nil
+	} else {
+		return ``, err
+	}
 }
 
 // Reload the currently active tab.
@@ -144,7 +222,7 @@ func (self *Commands) Resize(args *ResizeArgs) (*ResizeResponse, error) {
 		}
 	}
 
-	if _, err := self.browser.Tab().RPC(`Emulation`, `setDeviceMetricsOverride`, rpcArgs); err == nil {
+	if err := self.browser.Tab().SetDeviceMetrics(rpcArgs); err == nil {
 		return &ResizeResponse{
 			Width:  args.Width,
 			Height: args.Height,
@@ -154,10 +232,15 @@ func (self *Commands) Resize(args *ResizeArgs) (*ResizeResponse, error) {
 	}
 }
 
-// [SKIP]
-// Return all currently open tabs.
-func (self *Commands) Tabs() ([]browser.Tab, error) {
-	return nil, fmt.Errorf(`NI`)
+// Return the IDs of all currently open tabs.
+func (self *Commands) Tabs() ([]browser.TabID, error) {
+	ids := make([]browser.TabID, 0)
+
+	for _, tab := range self.browser.Tabs() {
+		ids = append(ids, browser.TabID(tab.ID()))
+	}
+
+	return ids, nil
 }
 
 // Navigate back through the current tab's history.
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-webfriend/browser"
+)
+
+type TlsArgs struct {
+	// Ignore certificate errors for all origins.
+	IgnoreErrors bool `json:"ignore_errors"`
+
+	// Ignore certificate errors only for origins matching these patterns (e.g.: "*.staging.example.com").
+	IgnoreOrigins []string `json:"ignore_origins"`
+
+	// The path to a file containing additional PEM-encoded certificate authorities to trust.
+	CABundle string `json:"ca_bundle"`
+
+	// The path to a PEM-encoded client certificate to present to hosts matching client_origins.
+	ClientCertificate string `json:"client_certificate"`
+
+	// The path to the PEM-encoded private key for client_certificate.  If omitted, the key is
+	// expected to be in the same file as the certificate.
+	ClientKey string `json:"client_key"`
+
+	// The URL patterns of requests that the client certificate should be presented to.
+	ClientOrigins []string `json:"client_origins"`
+}
+
+// Configure how TLS certificates are handled by the current tab.  Certificate errors can be
+// ignored for all or some origins, additional certificate authorities can be trusted, and a
+// client certificate can be presented to hosts that require mutual TLS.  Each call replaces the
+// previous configuration; calling this command with no arguments restores the default behavior
+// of failing on certificate errors and stops presenting client certificates.
+//
+// #### Examples
+//
+// ##### Ignore certificate errors on staging hosts only.
+// ```
+//
+//	tls {
+//	  ignore_origins: ['*.staging.example.com'],
+//	}
+//
+// ```
+//
+// ##### Trust an internal CA and present a client certificate to the API host.
+// ```
+//
+//	tls {
+//	  ca_bundle:          '/etc/ssl/internal-ca.pem',
+//	  client_certificate: '/etc/ssl/client.pem',
+//	  client_key:         '/etc/ssl/client.key',
+//	  client_origins:     ['https://api.internal.example.com/*'],
+//	}
+//
+// ```
+func (self *Commands) Tls(args *TlsArgs) error {
+	if args == nil {
+		args = &TlsArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	policy := &browser.CertificatePolicy{
+		IgnoreAll:     args.IgnoreErrors,
+		IgnoreOrigins: args.IgnoreOrigins,
+	}
+
+	if args.CABundle != `` {
+		if pemData, err := self.readFile(args.CABundle); err == nil {
+			if pool, err := browser.LoadCertificatePool(pemData); err == nil {
+				policy.RootCAs = pool
+			} else {
+				return fmt.Errorf("ca_bundle: %v", err)
+			}
+		} else {
+			return fmt.Errorf("ca_bundle: %v", err)
+		}
+	}
+
+	if err := self.browser.Tab().SetCertificatePolicy(policy); err != nil {
+		return err
+	}
+
+	// any previously-configured client certificates are replaced (or removed)
+	if err := self.browser.Tab().ClearClientCertificates(); err != nil {
+		return err
+	}
+
+	if args.ClientCertificate != `` {
+		var certificate tls.Certificate
+
+		if len(args.ClientOrigins) == 0 {
+			return fmt.Errorf("client_origins must be specified when using a client certificate")
+		}
+
+		if certPem, err := self.readFile(args.ClientCertificate); err == nil {
+			keyPem := certPem
+
+			if args.ClientKey != `` {
+				if keyPem, err = self.readFile(args.ClientKey); err != nil {
+					return fmt.Errorf("client_key: %v", err)
+				}
+			}
+
+			if certificate, err = tls.X509KeyPair(certPem, keyPem); err != nil {
+				return fmt.Errorf("client_certificate: %v", err)
+			}
+		} else {
+			return fmt.Errorf("client_certificate: %v", err)
+		}
+
+		for _, origin := range args.ClientOrigins {
+			if err := self.browser.Tab().AddClientCertificate(origin, certificate, policy.RootCAs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (self *Commands) readFile(filename string) ([]byte, error) {
+	if file, err := self.browser.GetReaderForPath(filename); err == nil {
+		defer file.Close()
+		return ioutil.ReadAll(file)
+	} else {
+		return nil, err
+	}
+}
+
+type Certificate struct {
+	// The TLS protocol version that was negotiated (e.g.: "TLS 1.3").
+	Protocol string `json:"protocol"`
+
+	// The key exchange algorithm that was used.
+	KeyExchange string `json:"keyExchange,omitempty"`
+
+	// The cipher that was negotiated.
+	Cipher string `json:"cipher"`
+
+	// The subject of the certificate.
+	SubjectName string `json:"subject"`
+
+	// The issuer of the certificate.
+	Issuer string `json:"issuer"`
+
+	// The Subject Alternative Names listed in the certificate.
+	SANs []string `json:"sans,omitempty"`
+
+	// When the certificate became valid.
+	ValidFrom time.Time `json:"validFrom"`
+
+	// When the certificate expires.
+	ValidTo time.Time `json:"validTo"`
+
+	// Whether the certificate is compliant with Certificate Transparency policy ("compliant", "not-compliant", or "unknown").
+	Transparency string `json:"transparency,omitempty"`
+}
+
+func certificateFromSecurityDetails(details *maputil.Map) *Certificate {
+	if details.String(`protocol`) == `` {
+		return nil
+	}
+
+	certificate := &Certificate{
+		Protocol:     details.String(`protocol`),
+		KeyExchange:  details.String(`keyExchange`),
+		Cipher:       details.String(`cipher`),
+		SubjectName:  details.String(`subjectName`),
+		Issuer:       details.String(`issuer`),
+		ValidFrom:    time.Unix(details.Int(`validFrom`), 0),
+		ValidTo:      time.Unix(details.Int(`validTo`), 0),
+		Transparency: details.String(`certificateTransparencyCompliance`),
+	}
+
+	for _, san := range details.Slice(`sanList`) {
+		certificate.SANs = append(certificate.SANs, fmt.Sprintf("%v", san))
+	}
+
+	return certificate
+}
@@ -9,10 +9,11 @@ import (
 	"github.com/ghetzel/go-stockutil/rxutil"
 	"github.com/ghetzel/go-stockutil/stringutil"
 	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/browser"
 	"github.com/ghetzel/go-webfriend/utils"
 )
 
-var rxKeyCodes = regexp.MustCompile(`(\[[^\]]*?\]|.)`)
+var rxKeyCodes = regexp.MustCompile(`(?s)(\[[^\]]*?\]|.)`)
 
 type TypeArgs struct {
 	Alt     bool `json:"alt"`
@@ -40,7 +41,9 @@ type TypeArgs struct {
 // page element.  The input text contains raw unicode characters that will be typed
 // literally, as well as key names (in accordance with the DOM pre-defined keynames
 // described at https://developer.mozilla.org/en-US/docs/Web/API/KeyboardEvent/key/Key_Values).
-// These sequences appear between square brackets "[" "]".
+// These sequences appear between square brackets "[" "]", and may also be key
+// combinations (e.g.: "[Control+a]").  Keys are reported with the key, code, and
+// key code of a US keyboard layout.
 //
 // Example: Type in the Konami code
 //
@@ -54,9 +57,9 @@ func (self *Commands) Type(input interface{}, args *TypeArgs) (string, error) {
 		args = &TypeArgs{}
 	}
 
-	var modifiers int
 	var symbols = rxutil.Match(rxKeyCodes, typeutil.String(input)).AllCaptures()
 	var text string
+	var tab = self.browser.Tab()
 
 	defaults.SetDefaults(args)
 
@@ -65,56 +68,41 @@ func (self *Commands) Type(input interface{}, args *TypeArgs) (string, error) {
 	args.Delay = utils.FudgeDuration(args.Delay)
 	args.DelayJitter = utils.FudgeDuration(args.DelayJitter)
 
-	// modifiers: Bit field representing pressed modifier keys. Alt=1, Ctrl=2, Meta/Command=4, Shift=8 (default: 0)
-	if args.Alt {
-		modifiers |= 1
-	}
-	if args.Control {
-		modifiers |= 2
-	}
-	if args.Meta {
-		modifiers |= 4
-	}
-	if args.Shift {
-		modifiers |= 8
+	var config = &browser.KeyboardActionConfig{
+		Alt:      args.Alt,
+		Control:  args.Control,
+		Meta:     args.Meta,
+		Shift:    args.Shift,
+		IsKeypad: args.IsKeypad,
 	}
 
 	for _, symbol := range symbols {
-		var keyEvent = map[string]interface{}{
-			`type`:      `keyDown`,
-			`isKeypad`:  args.IsKeypad,
-			`modifiers`: modifiers,
-		}
+		var holdFor = args.KeyDownTime + time.Duration(float64(args.KeyDownJitter)*rand.Float64())
 
-		if stringutil.IsSurroundedBy(symbol, `[`, `]`) {
-			keyEvent[`key`] = stringutil.Unwrap(symbol, `[`, `]`)
-		} else {
-			text += string(symbol)
-			keyEvent[`text`] = string(symbol)
-		}
+		if stringutil.IsSurroundedBy(symbol, `[`, `]`) && len(symbol) > 2 {
+			// named keys and key combinations (e.g.: "[Enter]", "[Control+a]")
+			if err := tab.PressKeys(stringutil.Unwrap(symbol, `[`, `]`), holdFor, config); err != nil {
+				return ``, err
+			}
+		} else if _, ok := browser.LookupKey(symbol); ok {
+			text += symbol
 
-		// send the keyDown event
-		if _, err := self.browser.Tab().RPC(`Input`, `dispatchKeyEvent`, keyEvent); err == nil {
-			if args.KeyDownTime > 0 {
-				time.Sleep(
-					args.KeyDownTime + (time.Duration(float64(args.KeyDownJitter)*rand.Float64()) * time.Millisecond),
-				)
+			if err := tab.PressKeys(symbol, holdFor, config); err != nil {
+				return ``, err
 			}
 		} else {
-			return ``, err
-		}
+			// characters that aren't on the keyboard are entered directly
+			text += symbol
 
-		// send the keyUp event
-		if _, err := self.browser.Tab().RPC(`Input`, `dispatchKeyEvent`, map[string]interface{}{
-			`type`: `keyUp`,
-		}); err != nil {
-			return ``, err
+			if err := tab.InsertText(symbol); err != nil {
+				return ``, err
+			}
 		}
 
 		// simulate the time between key presses
 		if args.Delay > 0 {
 			time.Sleep(
-				args.Delay + (time.Duration(float64(args.DelayJitter)*rand.Float64()) * time.Millisecond),
+				args.Delay + time.Duration(float64(args.DelayJitter)*rand.Float64()),
 			)
 		}
 	}
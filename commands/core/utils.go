@@ -4,6 +4,7 @@ import (
 	defaults "github.com/ghetzel/go-defaults"
 	"github.com/ghetzel/go-stockutil/pathutil"
 	"github.com/ghetzel/go-stockutil/stringutil"
+	"github.com/ghetzel/go-stockutil/typeutil"
 	"github.com/ghetzel/go-webfriend/browser"
 	"github.com/ghetzel/go-webfriend/dom"
 	"github.com/jdxcode/netrc"
@@ -17,11 +18,45 @@ func (self *Commands) Rpc(method string, args map[string]interface{}) (interface
 	return self.browser.Tab().RPC(mod, meth, args)
 }
 
-// [SKIP]
 // Change the current selector scope to be rooted at the given element. If
-// selector is empty, the scope is set to the document element (i.e.: global).
-func (self *Commands) SwitchRoot(selector dom.Selector) error {
-	return browser.NotImplemented
+// selector is "none", the scope is set to the document element (i.e.: global).
+// If the matching element hosts a shadow root (open or closed), the scope is
+// set to that shadow root instead.  The scope is reset whenever the page navigates.
+//
+// #### Examples
+//
+// ##### Fill in a field inside of a web component, then return to the document.
+// ```
+// switch_root 'login-form'
+// field 'input[name="username"]' { value: 'user' }
+// switch_root none
+// ```
+func (self *Commands) SwitchRoot(selector dom.Selector) (*dom.Element, error) {
+	tab := self.browser.Tab()
+
+	if selector.IsNone() {
+		tab.SetRoot(nil)
+		return nil, nil
+	}
+
+	if elements, err := self.Select(selector, nil); err == nil && len(elements) == 1 {
+		root := elements[0]
+
+		if shadowRoot, err := tab.ShadowRootOf(root); err == nil {
+			if shadowRoot != nil {
+				root = shadowRoot
+			}
+		} else {
+			return nil, err
+		}
+
+		tab.SetRoot(root)
+		return root, nil
+	} else if l := len(elements); l > 1 {
+		return nil, dom.TooManyMatchesErr(selector, 1, l)
+	} else {
+		return nil, err
+	}
 }
 
 type HighlightArgs struct {
@@ -38,32 +73,49 @@ type HighlightArgs struct {
 	A float64 `json:"a" default:"0.5"`
 }
 
-// Highlight the node matching the given selector, or clear all highlights if
-// the selector is "none"
-func (self *Commands) Highlight(selector interface{}, args *HighlightArgs) error {
-	// if args == nil {
-	// 	args = &HighlightArgs{}
-	// }
-
-	// defaults.SetDefaults(args)
-
-	// if elements, err := self.browser.Tab().ElementQuery(selector, nil); err == nil {
-	// 	if len(elements) == 0 {
-	// 		return self.browser.Tab().AsyncRPC(`DOM`, `hideHighlight`, nil)
-	// 	} else {
-	// 		for _, element := range elements {
-	// 			if err := element.Highlight(args.R, args.G, args.B, args.A); err != nil {
-	// 				return err
-	// 			}
-	// 		}
-
-	// 		return nil
-	// 	}
-	// } else {
-	// 	return err
-	// }
-
-	return browser.NotImplemented
+// Highlight the nodes matching the given selector, or clear all highlights if
+// the selector is "none".  Highlights are drawn by the browser, so they appear
+// in screenshots and in the debug server's screencast.
+//
+// #### Examples
+//
+// ##### Highlight all invalid form fields in red, then take a screenshot.
+// ```
+//
+//	highlight 'input:invalid' {
+//	  r: 255,
+//	  g: 0,
+//	  b: 0,
+//	  a: 0.4,
+//	}
+//
+// page::screenshot '/tmp/invalid-fields.png'
+// highlight none
+// ```
+func (self *Commands) Highlight(selector interface{}, args *HighlightArgs) ([]*dom.Element, error) {
+	if args == nil {
+		args = &HighlightArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	tab := self.browser.Tab()
+	sel := dom.Selector(typeutil.String(selector))
+
+	if selector == nil || sel.IsNone() {
+		return nil, tab.ClearHighlights()
+	}
+
+	if elements, err := tab.ElementQuery(sel, nil); err == nil {
+		return elements, tab.HighlightElements(elements, browser.HighlightColor{
+			R: args.R,
+			G: args.G,
+			B: args.B,
+			A: args.A,
+		})
+	} else {
+		return nil, err
+	}
 }
 
 type InspectArgs struct {
@@ -90,6 +142,19 @@ type InspectArgs struct {
 }
 
 // Retrieve the element at the given coordinates, optionally highlighting it.
+//
+// #### Examples
+//
+// ##### Find out what is covering the center of the page.
+// ```
+//
+//	inspect {
+//	  x: 400,
+//	  y: 300,
+//	} -> $element
+//
+// log "Found <{element[name]}> at {element[path]}"
+// ```
 func (self *Commands) Inspect(args *InspectArgs) (*dom.Element, error) {
 	if args == nil {
 		args = &InspectArgs{}
@@ -97,26 +162,24 @@ func (self *Commands) Inspect(args *InspectArgs) (*dom.Element, error) {
 
 	defaults.SetDefaults(args)
 
-	// if rv, err := self.browser.Tab().RPC(`DOM`, `getNodeForLocation`, map[string]interface{}{
-	// 	`x`: int(args.X),
-	// 	`y`: int(args.Y),
-	// }); err == nil {
-	// 	// if element, ok := self.browser.Tab().DOM().Element(int(rv.R().Int(`backendNodeId`))); ok {
-	// 	// 	if args.Highlight {
-	// 	// 		if err := element.Highlight(args.R, args.G, args.B, args.A); err != nil {
-	// 	// 			return nil, err
-	// 	// 		}
-	// 	// 	}
-
-	// 	// 	return element, nil
-	// 	// } else {
-	// 	// }
-	// 	return nil, fmt.Errorf("No element was found at the given coordinates.")
-	// } else {
-	// 	return nil, err
-	// }
-
-	return nil, browser.NotImplemented
+	tab := self.browser.Tab()
+
+	if element, err := tab.ElementAtPoint(args.X, args.Y); err == nil {
+		if args.Highlight {
+			if err := tab.HighlightElements([]*dom.Element{element}, browser.HighlightColor{
+				R: args.R,
+				G: args.G,
+				B: args.B,
+				A: args.A,
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		return element, nil
+	} else {
+		return nil, err
+	}
 }
 
 // Immediately close the browser without error or delay.
@@ -1,9 +1,12 @@
 package core
 
 import (
+	"fmt"
+	"regexp"
 	"time"
 
 	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-webfriend/dom"
 	"github.com/ghetzel/go-webfriend/utils"
 )
 
@@ -39,3 +42,184 @@ func (self *Commands) WaitFor(event string, args *WaitForArgs) error {
 func (self *Commands) WaitForLoad(args *WaitForArgs) error {
 	return self.WaitFor(WaitForLoadEventName, args)
 }
+
+type WaitForElementArgs struct {
+	// The condition to wait for; one of "attached", "detached", "visible", "hidden", "enabled", or "stable".
+	State string `json:"state" default:"visible"`
+
+	// If provided, this represents a regular expression that the text of matching elements must match.
+	MatchText string `json:"match_text"`
+
+	// The minimum number of matching elements that must satisfy the condition.
+	Count int `json:"count" default:"1"`
+
+	// How long an element's position must remain unchanged for it to be considered "stable".
+	StableFor time.Duration `json:"stable_for" default:"250ms"`
+
+	// The timeout before we stop waiting for the condition to be met.
+	Timeout time.Duration `json:"timeout" default:"30s"`
+
+	// The polling interval between element re-checks.
+	Interval time.Duration `json:"interval" default:"100ms"`
+}
+
+// Wait for elements matching the given selector to satisfy a condition, returning the elements
+// that satisfy it.  The possible conditions are:
+//
+// - "attached": at least _count_ matching elements exist in the page.
+// - "detached": no matching elements exist in the page.
+// - "visible": at least _count_ matching elements are visible.
+// - "hidden": no matching elements are visible (or none exist).
+// - "enabled": at least _count_ matching elements are visible and enabled.
+// - "stable": at least _count_ matching elements are visible and have not moved for _stable_for_.
+//
+// If the condition is not met before the timeout, the error describes the last state that was
+// observed.
+//
+// #### Examples
+//
+// ##### Wait for a spinner to go away, then for the results to show up.
+// ```
+//
+//	wait_for_element '.loading-spinner' {
+//	  state: 'hidden',
+//	}
+//
+//	wait_for_element '#results li' {
+//	  count:      10,
+//	  match_text: '\d+ reviews',
+//	} -> $results
+//
+// ```
+func (self *Commands) WaitForElement(selector dom.Selector, args *WaitForElementArgs) ([]*dom.Element, error) {
+	if args == nil {
+		args = &WaitForElementArgs{}
+	}
+
+	defaults.SetDefaults(args)
+	args.StableFor = utils.FudgeDuration(args.StableFor)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
+	args.Interval = utils.FudgeDuration(args.Interval)
+
+	var textPattern *regexp.Regexp
+	var observed = `no elements matched`
+	var boxes = make(map[string]dom.Dimensions)
+	var unmovedSince = make(map[string]time.Time)
+
+	switch args.State {
+	case `attached`, `detached`, `visible`, `hidden`, `enabled`, `stable`:
+		break
+	default:
+		return nil, fmt.Errorf("Unsupported element state %q", args.State)
+	}
+
+	if args.MatchText != `` {
+		if rx, err := regexp.Compile(args.MatchText); err == nil {
+			textPattern = rx
+		} else {
+			return nil, fmt.Errorf("match_text: %v", err)
+		}
+	}
+
+	started := time.Now()
+
+	for time.Since(started) <= args.Timeout {
+		if elements, err := self.browser.Tab().ElementQuery(selector, nil); err == nil {
+			satisfying := make([]*dom.Element, 0)
+			visible := 0
+			enabled := 0
+
+			if textPattern != nil {
+				var matches = make([]*dom.Element, 0)
+
+				for _, element := range elements {
+					if textPattern.MatchString(element.Text) {
+						matches = append(matches, element)
+					}
+				}
+
+				elements = matches
+			}
+
+			for _, element := range elements {
+				if element.Visible {
+					visible += 1
+				}
+
+				if element.Enabled {
+					enabled += 1
+				}
+
+				switch args.State {
+				case `attached`:
+					satisfying = append(satisfying, element)
+
+				case `visible`:
+					if element.Visible {
+						satisfying = append(satisfying, element)
+					}
+
+				case `hidden`:
+					if !element.Visible {
+						satisfying = append(satisfying, element)
+					}
+
+				case `enabled`:
+					if element.Visible && element.Enabled {
+						satisfying = append(satisfying, element)
+					}
+
+				case `stable`:
+					if element.Visible && element.Box != nil {
+						// elements are identified by their path, since each query returns new handles
+						if last, ok := boxes[element.Path]; !ok || last != *element.Box {
+							boxes[element.Path] = *element.Box
+							unmovedSince[element.Path] = time.Now()
+						} else if time.Since(unmovedSince[element.Path]) >= args.StableFor {
+							satisfying = append(satisfying, element)
+						}
+					}
+				}
+			}
+
+			switch args.State {
+			case `detached`:
+				if len(elements) == 0 {
+					return elements, nil
+				}
+
+			case `hidden`:
+				if visible == 0 {
+					return satisfying, nil
+				}
+
+			default:
+				if len(satisfying) >= args.Count {
+					return satisfying, nil
+				}
+			}
+
+			observed = fmt.Sprintf(
+				"%d element(s) matched (%d visible, %d enabled)",
+				len(elements),
+				visible,
+				enabled,
+			)
+
+			if args.State == `stable` && visible > 0 {
+				observed += `, but they were still moving`
+			}
+		} else {
+			observed = err.Error()
+		}
+
+		time.Sleep(args.Interval)
+	}
+
+	return nil, fmt.Errorf(
+		"Timed out waiting for %q to be %s: %s",
+		selector,
+		args.State,
+		observed,
+	)
+}
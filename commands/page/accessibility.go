@@ -0,0 +1,149 @@
+package page
+
+import (
+	"fmt"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+type AccessibilityArgs struct {
+	// Only return the part of the tree beneath the first element matching this selector.
+	Root dom.Selector `json:"root"`
+
+	// Include nodes that are ignored by assistive technologies, or that only exist for layout
+	// purposes (e.g.: unnamed <div> and <span> elements).
+	IncludeIgnored bool `json:"include_ignored"`
+
+	// The maximum depth of the returned tree (0 means unlimited).
+	Depth int `json:"depth"`
+}
+
+type AccessibilityNode struct {
+	// The ARIA role of the node (e.g.: "button", "link", "heading").
+	Role string `json:"role"`
+
+	// The accessible name of the node.
+	Name string `json:"name,omitempty"`
+
+	// The value of the node (e.g.: the contents of a text field).
+	Value interface{} `json:"value,omitempty"`
+
+	// The accessible description of the node.
+	Description string `json:"description,omitempty"`
+
+	// States and properties of the node (e.g.: "focusable", "checked", "expanded", "level").
+	States map[string]interface{} `json:"states,omitempty"`
+
+	// The node's children.
+	Children []*AccessibilityNode `json:"children,omitempty"`
+}
+
+// Return the accessibility tree of the current page, as it would be presented to assistive
+// technologies such as screen readers.  Each node describes its role, name, value, and states.
+//
+// #### Examples
+//
+// ##### Retrieve the accessibility tree of the main navigation.
+// ```
+//
+//	page::accessibility {
+//	  root: 'nav#main',
+//	} -> $tree
+//
+// ```
+//
+// ##### Use role selectors to click a button by its accessible name.
+// ```
+// click '@role[button "Sign in"]'
+// ```
+func (self *Commands) Accessibility(args *AccessibilityArgs) (*AccessibilityNode, error) {
+	var root *dom.Element
+
+	if args == nil {
+		args = &AccessibilityArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	if !args.Root.IsNone() {
+		if elements, err := self.browser.Tab().ElementQuery(args.Root, nil); err == nil {
+			if len(elements) == 0 {
+				return nil, fmt.Errorf("root: no elements matched %q", args.Root)
+			}
+
+			root = elements[0]
+		} else {
+			return nil, fmt.Errorf("root: %v", err)
+		}
+	}
+
+	if tree, err := self.browser.Tab().AccessibilityTree(root); err == nil {
+		// the top of the tree is always returned, even if it would otherwise be collapsed
+		top := &AccessibilityNode{
+			Role:        tree.Role,
+			Name:        tree.Name,
+			Value:       tree.Value,
+			Description: tree.Description,
+			States:      tree.Properties,
+		}
+
+		for _, child := range tree.Children {
+			top.Children = append(top.Children, accessibilityNodes(child, args, 1)...)
+		}
+
+		return top, nil
+	} else {
+		return nil, err
+	}
+}
+
+// convert the given node into zero or more output nodes, collapsing uninteresting nodes into their children
+func accessibilityNodes(axnode *browser.AXNode, args *AccessibilityArgs, depth int) []*AccessibilityNode {
+	nodes := make([]*AccessibilityNode, 0)
+
+	if axnode.Role == `InlineTextBox` {
+		return nodes
+	}
+
+	if !args.IncludeIgnored && isUninterestingAXNode(axnode) {
+		for _, child := range axnode.Children {
+			nodes = append(nodes, accessibilityNodes(child, args, depth)...)
+		}
+
+		return nodes
+	}
+
+	node := &AccessibilityNode{
+		Role:        axnode.Role,
+		Name:        axnode.Name,
+		Value:       axnode.Value,
+		Description: axnode.Description,
+	}
+
+	if len(axnode.Properties) > 0 {
+		node.States = axnode.Properties
+	}
+
+	if args.Depth <= 0 || depth < args.Depth {
+		for _, child := range axnode.Children {
+			node.Children = append(node.Children, accessibilityNodes(child, args, depth+1)...)
+		}
+	}
+
+	return append(nodes, node)
+}
+
+func isUninterestingAXNode(axnode *browser.AXNode) bool {
+	if axnode.Ignored {
+		return true
+	}
+
+	switch axnode.Role {
+	case `generic`, `none`, `presentation`, `GenericContainer`, `LineBreak`:
+		return (axnode.Name == ``)
+	}
+
+	return false
+}
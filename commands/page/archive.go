@@ -0,0 +1,120 @@
+package page
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-webfriend/browser"
+)
+
+type ArchiveArgs struct {
+	// The format to save the page in.  One of "mhtml" (a web archive containing the page and all of
+	// its resources), "html" (a single HTML file with all computed styles, frames, and images
+	// inlined), or "snapshot" (the raw DOM snapshot, including layout and computed styles, as JSON).
+	Format string `json:"format" default:"mhtml"`
+
+	// The computed styles to include when using the "snapshot" format.
+	Styles []string `json:"styles"`
+}
+
+type ArchiveResponse struct {
+	// The format the page was saved in.
+	Format string `json:"format"`
+
+	// The path that the archive was written to.
+	Path string `json:"path"`
+
+	// The size of the archive (in bytes).
+	Size int64 `json:"size"`
+}
+
+// Save the current page (as it is currently rendered) to a single file that can be reopened
+// offline.  Unlike page::source, the archive includes external stylesheets, images, frames, and
+// the state of the page after any scripts have run.
+//
+// #### Examples
+//
+// ##### Save the page as an MHTML web archive.
+// ```
+// page::archive '/evidence/checkout.mhtml'
+// ```
+//
+// ##### Save the page as a self-contained HTML file.
+// ```
+//
+//	page::archive '/evidence/checkout.html' {
+//	  format: 'html',
+//	} -> $archive
+//
+// ```
+func (self *Commands) Archive(destination string, args *ArchiveArgs) (*ArchiveResponse, error) {
+	var data []byte
+	var err error
+
+	if args == nil {
+		args = &ArchiveArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	if destination == `` {
+		return nil, fmt.Errorf("A destination for the archive must be specified")
+	}
+
+	tab := self.browser.Tab()
+
+	switch args.Format {
+	case `mhtml`:
+		data, err = tab.CaptureMHTML()
+	case `html`:
+		data, err = tab.CaptureHTML()
+	case `snapshot`:
+		styles := args.Styles
+
+		if len(styles) == 0 {
+			styles = browser.ArchiveComputedStyles
+		}
+
+		var snapshot map[string]interface{}
+
+		if snapshot, err = tab.CaptureDOMSnapshot(styles); err == nil {
+			data, err = json.Marshal(snapshot)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported archive format %q", args.Format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ArchiveResponse{
+		Format: args.Format,
+		Path:   destination,
+		Size:   int64(len(data)),
+	}
+
+	var writer io.Writer
+
+	if newPath, w, err := self.browser.GetWriterForPath(destination); err == nil && w != nil {
+		writer = w
+		response.Path = newPath
+	} else if file, err := os.Create(destination); err == nil {
+		writer = file
+	} else {
+		return nil, err
+	}
+
+	if closer, ok := writer.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,338 @@
+package page
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-stockutil/pathutil"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+// How different two pixels may be (from 0 to 1) before they are considered mismatched, if no
+// threshold is given to compare_screenshot.
+var DefaultCompareThreshold = 0.1
+
+type CompareScreenshotArgs struct {
+	// If specified, just the matching element will be captured and compared.
+	Selector dom.Selector `json:"selector,omitempty"`
+
+	// Determines how to handle multiple elements that are matched by Selector.
+	// May be "tallest" or "first".
+	Use string `json:"use,omitempty" default:"tallest"`
+
+	// The width of the region to capture (defaults to the rest of the page's width).
+	Width int `json:"width"`
+
+	// The height of the region to capture (defaults to the rest of the page's height).
+	Height int `json:"height"`
+
+	// The horizontal position (relative to the top-left corner of the page) of the region to capture.
+	X int `json:"x" default:"-1"`
+
+	// The vertical position (relative to the top-left corner of the page) of the region to capture.
+	Y int `json:"y" default:"-1"`
+
+	// Capture only the visible part of the page (rather than the entire page) when no selector or
+	// region is given.
+	ViewportOnly bool `json:"viewport_only"`
+
+	// The device scale factor of the screenshot.  This must match the scale the baseline was
+	// captured at.
+	Scale float64 `json:"scale" default:"1"`
+
+	// When capturing an element, include this many pixels of the page around it.
+	Padding int `json:"padding"`
+
+	// Selectors for elements whose contents should be ignored (e.g.: ads, timestamps, avatars).
+	Mask []string `json:"mask"`
+
+	// How different two pixels may be before they are considered mismatched, from 0 (must be
+	// identical) to 1 (anything goes).  Defaults to DefaultCompareThreshold.
+	Threshold *float64 `json:"threshold"`
+
+	// The percentage of pixels that may differ before the comparison fails.
+	MaxMismatch float64 `json:"max_mismatch"`
+
+	// Count pixels that appear to differ only because of anti-aliasing as mismatched.
+	IncludeAntialiasing bool `json:"include_antialiasing"`
+
+	// Where to write an image highlighting the differences.  Defaults to the baseline filename
+	// with ".diff.png" in place of its extension.
+	Diff string `json:"diff"`
+
+	// Replace the baseline with the new screenshot (after comparing against it).
+	Update bool `json:"update"`
+
+	// Whether to return an error if the comparison fails.
+	Strict bool `json:"strict"`
+}
+
+type CompareScreenshotResponse struct {
+	// Details about the element that matched the given selector (if any).
+	Element *dom.Element `json:"element,omitempty"`
+
+	// The path to the baseline image.
+	Baseline string `json:"baseline"`
+
+	// The path the diff image was written to (if a comparison was made).
+	Diff string `json:"diff,omitempty"`
+
+	// Whether the baseline did not exist, and was created from this screenshot.
+	Created bool `json:"created"`
+
+	// Whether the screenshot matched the baseline closely enough.
+	Passed bool `json:"passed"`
+
+	// The percentage of pixels that differed.
+	Mismatch float64 `json:"mismatch"`
+
+	// The number of pixels that differed.
+	MismatchedPixels int `json:"mismatched_pixels"`
+
+	// The number of pixels that differed only because of anti-aliasing (and were ignored).
+	AntialiasedPixels int `json:"antialiased_pixels"`
+
+	// The number of pixels that were compared (i.e.: were not masked).
+	ComparedPixels int `json:"compared_pixels"`
+
+	// The width of the screenshot (in pixels).
+	Width int `json:"width"`
+
+	// The height of the screenshot (in pixels).
+	Height int `json:"height"`
+
+	// The width of the baseline image (in pixels).
+	BaselineWidth int `json:"baseline_width"`
+
+	// The height of the baseline image (in pixels).
+	BaselineHeight int `json:"baseline_height"`
+}
+
+// Capture the page (or an element on it) and compare it against a baseline PNG image, returning
+// the percentage of pixels that differ.  Pixels are compared by their perceived color, and
+// differences that are only the result of anti-aliasing are ignored.  An image highlighting the
+// differences in red (and anti-aliasing in yellow) is written alongside the baseline.
+//
+// If the baseline does not exist, the screenshot is saved as the new baseline and the comparison
+// passes.
+//
+// #### Examples
+//
+// ##### Compare the homepage against a baseline, ignoring ads and the current date.
+// ```
+// go 'https://example.com'
+//
+//	page::compare_screenshot 'baselines/home.png' {
+//	  mask:         ['.ad', '#today'],
+//	  max_mismatch: 0.5,
+//	  strict:       true,
+//	}
+//
+// ```
+//
+// ##### Compare a single component, and report how different it is.
+// ```
+//
+//	page::compare_screenshot 'baselines/nav.png' {
+//	  selector: 'nav',
+//	} -> $result
+//
+// log "Navigation differs by {result[mismatch]}% (see {result[diff]})"
+// ```
+func (self *Commands) CompareScreenshot(baseline string, args *CompareScreenshotArgs) (*CompareScreenshotResponse, error) {
+	if args == nil {
+		args = &CompareScreenshotArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	if baseline == `` {
+		return nil, fmt.Errorf("A baseline image path must be specified")
+	} else if expanded, err := pathutil.ExpandUser(baseline); err == nil {
+		baseline = expanded
+	} else {
+		return nil, err
+	}
+
+	captured := &ScreenshotResponse{}
+	response := &CompareScreenshotResponse{
+		Baseline: baseline,
+	}
+
+	data, err := self.captureScreenshot(&ScreenshotArgs{
+		Selector:     args.Selector,
+		Use:          args.Use,
+		Width:        args.Width,
+		Height:       args.Height,
+		X:            args.X,
+		Y:            args.Y,
+		Format:       `png`,
+		ViewportOnly: args.ViewportOnly,
+		Scale:        args.Scale,
+		Padding:      args.Padding,
+	}, captured)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Element = captured.Element
+
+	current, err := png.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode screenshot: %v", err)
+	}
+
+	response.Width = current.Bounds().Dx()
+	response.Height = current.Bounds().Dy()
+
+	// no baseline yet: this screenshot becomes the baseline
+	if !pathutil.FileExists(baseline) {
+		if err := writeImageFile(baseline, data); err != nil {
+			return nil, err
+		}
+
+		response.Created = true
+		response.Passed = true
+		response.BaselineWidth = response.Width
+		response.BaselineHeight = response.Height
+
+		return response, nil
+	}
+
+	var expected image.Image
+
+	if file, err := os.Open(baseline); err == nil {
+		defer file.Close()
+
+		if img, err := png.Decode(file); err == nil {
+			expected = img
+		} else {
+			return nil, fmt.Errorf("Failed to decode baseline %s: %v", baseline, err)
+		}
+	} else {
+		return nil, err
+	}
+
+	response.BaselineWidth = expected.Bounds().Dx()
+	response.BaselineHeight = expected.Bounds().Dy()
+
+	masks, err := self.screenshotMasks(args.Mask, captured, args.Scale)
+
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := DefaultCompareThreshold
+
+	if args.Threshold != nil {
+		threshold = *args.Threshold
+	}
+
+	diff := diffImages(expected, current, &imageDiffOptions{
+		Threshold:           threshold,
+		IncludeAntialiasing: args.IncludeAntialiasing,
+		Masks:               masks,
+	})
+
+	response.Mismatch = diff.MismatchPercent()
+	response.MismatchedPixels = diff.Mismatched
+	response.AntialiasedPixels = diff.Antialiased
+	response.ComparedPixels = diff.Compared
+	response.Passed = (response.Mismatch <= args.MaxMismatch)
+
+	if args.Diff != `` {
+		response.Diff = args.Diff
+	} else {
+		response.Diff = strings.TrimSuffix(baseline, filepath.Ext(baseline)) + `.diff.png`
+	}
+
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, diff.Image); err != nil {
+		return nil, err
+	}
+
+	if err := writeImageFile(response.Diff, buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	if args.Update {
+		if err := writeImageFile(baseline, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if args.Strict && !response.Passed {
+		return response, fmt.Errorf(
+			"Screenshot differs from baseline %s by %.2f%% (maximum %.2f%%); see %s",
+			baseline,
+			response.Mismatch,
+			args.MaxMismatch,
+			response.Diff,
+		)
+	}
+
+	return response, nil
+}
+
+// return the regions of the captured image (in image pixels) covered by elements matching the
+// given selectors
+func (self *Commands) screenshotMasks(selectors []string, captured *ScreenshotResponse, scale float64) ([]image.Rectangle, error) {
+	if len(selectors) == 0 {
+		return nil, nil
+	}
+
+	tab := self.browser.Tab()
+	viewport, _, err := tab.LayoutMetrics()
+
+	if err != nil {
+		return nil, err
+	}
+
+	masks := make([]image.Rectangle, 0)
+
+	for _, selector := range selectors {
+		if elements, err := tab.ElementQuery(dom.Selector(selector), nil); err == nil {
+			for _, element := range elements {
+				if box, err := self.elementPosition(element); err == nil {
+					// element boxes are relative to the viewport, the image is relative to the captured region
+					left := (float64(box.Left) + viewport.X - float64(captured.X)) * scale
+					top := (float64(box.Top) + viewport.Y - float64(captured.Y)) * scale
+
+					masks = append(masks, image.Rect(
+						int(math.Floor(left)),
+						int(math.Floor(top)),
+						int(math.Ceil(left+float64(box.Width)*scale)),
+						int(math.Ceil(top+float64(box.Height)*scale)),
+					))
+				} else {
+					return nil, fmt.Errorf("mask %v: %v", selector, err)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("mask %v: %v", selector, err)
+		}
+	}
+
+	return masks, nil
+}
+
+func writeImageFile(filename string, data []byte) error {
+	if dir := filepath.Dir(filename); dir != `` {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(filename, data, 0644)
+}
@@ -0,0 +1,180 @@
+package page
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-stockutil/pathutil"
+	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/utils"
+)
+
+type Download struct {
+	// The unique ID of the download.
+	ID string `json:"id"`
+
+	// The URL the file was downloaded from.
+	URL string `json:"url"`
+
+	// The filename suggested by the server (or derived from the URL).
+	Filename string `json:"filename"`
+
+	// The size of the downloaded file (in bytes).
+	Size int64 `json:"size"`
+
+	// The MIME type of the downloaded file.
+	MimeType string `json:"mimetype"`
+
+	// The filesystem path the downloaded file was saved to.
+	Path string `json:"path"`
+
+	// The state of the download; one of "inProgress", "completed", or "canceled".
+	State string `json:"state"`
+
+	// How long the download took to complete.
+	Took time.Duration `json:"took,omitempty"`
+}
+
+func downloadFromBrowser(download *browser.Download) *Download {
+	out := &Download{
+		ID:       download.ID,
+		URL:      download.URL,
+		Filename: download.SuggestedFilename,
+		Size:     download.ReceivedBytes,
+		MimeType: download.MimeType,
+		Path:     download.Path,
+		State:    string(download.State),
+	}
+
+	if !download.CompletedAt.IsZero() {
+		out.Took = download.CompletedAt.Sub(download.StartedAt)
+	}
+
+	return out
+}
+
+// List all downloads that have been started by the current page.
+func (self *Commands) Downloads() ([]*Download, error) {
+	downloads := make([]*Download, 0)
+
+	for _, download := range self.browser.Tab().Downloads() {
+		downloads = append(downloads, downloadFromBrowser(download))
+	}
+
+	return downloads, nil
+}
+
+type WaitForDownloadArgs struct {
+	// If specified, the downloaded file will be moved to this path.  If the path is an existing
+	// directory (or ends in a "/"), the file will be placed in that directory using the suggested filename.
+	Destination string `json:"destination"`
+
+	// The timeout before we stop waiting for the download to finish.
+	Timeout time.Duration `json:"timeout" default:"60s"`
+
+	// The polling interval between download re-checks.
+	Interval time.Duration `json:"interval" default:"125ms"`
+}
+
+// Wait for the next download started by the page to finish, then return details about the
+// downloaded file.  Each download is only returned once, so successive calls will return
+// successive downloads.
+//
+// #### Examples
+//
+// ##### Click an export button and save the resulting CSV file.
+// ```
+// click '#export-csv'
+//
+//	page::wait_for_download {
+//	  destination: '/data/exports/',
+//	} -> $download
+//
+// log "Saved {download[filename]} ({download[size]} bytes) to {download[path]}"
+// ```
+func (self *Commands) WaitForDownload(args *WaitForDownloadArgs) (*Download, error) {
+	if args == nil {
+		args = &WaitForDownloadArgs{}
+	}
+
+	defaults.SetDefaults(args)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
+	args.Interval = utils.FudgeDuration(args.Interval)
+
+	started := time.Now()
+
+	for time.Since(started) <= args.Timeout {
+		if download, ok := self.browser.Tab().NextFinishedDownload(); ok {
+			response := downloadFromBrowser(download)
+
+			if download.State == browser.DownloadCanceled {
+				return response, fmt.Errorf("Download of %v was canceled", download.URL)
+			}
+
+			if args.Destination != `` {
+				if newPath, err := moveDownload(download, args.Destination); err == nil {
+					response.Path = newPath
+				} else {
+					return response, err
+				}
+			}
+
+			return response, nil
+		}
+
+		time.Sleep(args.Interval)
+	}
+
+	return nil, fmt.Errorf("Timed out waiting for download to finish")
+}
+
+func moveDownload(download *browser.Download, destination string) (string, error) {
+	if expanded, err := pathutil.ExpandUser(destination); err == nil {
+		destination = expanded
+	} else {
+		return ``, err
+	}
+
+	if strings.HasSuffix(destination, `/`) || pathutil.DirExists(destination) {
+		filename := download.SuggestedFilename
+
+		if filename == `` {
+			filename = download.ID
+		}
+
+		destination = filepath.Join(destination, filepath.Base(filename))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return ``, err
+	}
+
+	// rename is fastest, but won't work across filesystems; so fallback to copying
+	if err := os.Rename(download.Path, destination); err == nil {
+		return destination, nil
+	}
+
+	if src, err := os.Open(download.Path); err == nil {
+		defer src.Close()
+
+		if dest, err := os.Create(destination); err == nil {
+			defer dest.Close()
+
+			if _, err := io.Copy(dest, src); err != nil {
+				return ``, err
+			}
+
+			os.Remove(download.Path)
+			return destination, nil
+		} else {
+			return ``, err
+		}
+	} else {
+		return ``, err
+	}
+}
@@ -0,0 +1,139 @@
+package page
+
+import (
+	"fmt"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/dom"
+)
+
+type ExtractArgs struct {
+	// If specified, all fields in the schema are extracted from beneath the first element matching
+	// this selector instead of from the whole page.
+	Root dom.Selector `json:"root"`
+}
+
+// Extract structured data from the page in a single step.  The schema is an object whose keys are
+// the names of the fields to extract, and whose values describe how to extract them.  A value can
+// be a selector (in which case the text of the first matching element is extracted), or an object
+// with the following keys:
+//
+// - "selector": the elements to extract the value from (any supported selector).
+// - "attribute": read this attribute instead of the element's text.
+// - "type": one of "text" (the default), "html", "number", or "exists".
+// - "match": a regular expression the value must match; the first capture group is extracted.
+// - "multiple": extract a list of values from all matching elements.
+// - "fields": a nested schema to extract from beneath each matching element.
+// - "default": the value to use if nothing could be extracted.
+//
+// #### Examples
+//
+// ##### Extract product details and a list of reviews.
+// ```
+//
+//	page::extract {
+//	  title: 'h1',
+//	  price: {
+//	    selector: '.price',
+//	    type:     'number',
+//	  },
+//	  image: {
+//	    selector:  'img.hero',
+//	    attribute: 'src',
+//	  },
+//	  in_stock: {
+//	    selector: '.add-to-cart',
+//	    type:     'exists',
+//	  },
+//	  tags: {
+//	    selector: '.tag',
+//	    multiple: true,
+//	  },
+//	  reviews: {
+//	    selector: '.review',
+//	    multiple: true,
+//	    fields: {
+//	      author: '.author',
+//	      rating: {
+//	        selector:  '.stars',
+//	        attribute: 'data-rating',
+//	        type:      'number',
+//	        default:   0,
+//	      },
+//	    },
+//	  },
+//	} -> $product
+//
+// log "{product[title]} costs {product[price]}"
+// ```
+func (self *Commands) Extract(schema map[string]interface{}, args *ExtractArgs) (map[string]interface{}, error) {
+	var root *dom.Element
+
+	if args == nil {
+		args = &ExtractArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	if len(schema) == 0 {
+		return nil, fmt.Errorf("A schema describing the data to extract must be specified")
+	}
+
+	if fields, err := parseExtractSchema(schema); err == nil {
+		if !args.Root.IsNone() {
+			if elements, err := self.browser.Tab().ElementQuery(args.Root, nil); err == nil {
+				if len(elements) == 0 {
+					return nil, fmt.Errorf("root: no elements matched %q", args.Root)
+				}
+
+				root = elements[0]
+			} else {
+				return nil, fmt.Errorf("root: %v", err)
+			}
+		}
+
+		return self.browser.Tab().Extract(fields, root)
+	} else {
+		return nil, err
+	}
+}
+
+func parseExtractSchema(schema map[string]interface{}) (map[string]*browser.ExtractField, error) {
+	fields := make(map[string]*browser.ExtractField)
+
+	for key, spec := range schema {
+		if typeutil.IsMap(spec) {
+			specM := maputil.M(spec)
+			field := &browser.ExtractField{
+				Selector:  dom.Selector(specM.String(`selector`)),
+				Attribute: specM.String(`attribute`),
+				Type:      specM.String(`type`, `text`),
+				Match:     specM.String(`match`),
+				Multiple:  specM.Bool(`multiple`),
+				Default:   specM.Get(`default`).Value,
+			}
+
+			if subschema := specM.Get(`fields`); typeutil.IsMap(subschema.Value) {
+				if subfields, err := parseExtractSchema(maputil.M(subschema.Value).MapNative()); err == nil {
+					field.Fields = subfields
+				} else {
+					return nil, fmt.Errorf("%s: %v", key, err)
+				}
+			}
+
+			fields[key] = field
+		} else if selector := typeutil.String(spec); selector != `` {
+			fields[key] = &browser.ExtractField{
+				Selector: dom.Selector(selector),
+				Type:     `text`,
+			}
+		} else {
+			return nil, fmt.Errorf("%s: a selector or field description must be specified", key)
+		}
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,281 @@
+package page
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// the largest possible YIQ color distance between two pixels
+const maxColorDelta = 35215.0
+
+var (
+	diffMismatchColor    = color.NRGBA{255, 0, 0, 255}
+	diffAntialiasedColor = color.NRGBA{255, 255, 0, 255}
+	diffMaskedColor      = color.NRGBA{160, 200, 255, 255}
+)
+
+type imageDiffOptions struct {
+	// how different two pixels may be (0-1) before they are considered mismatched
+	Threshold float64
+
+	// whether pixels that appear to differ only by anti-aliasing count as mismatched
+	IncludeAntialiasing bool
+
+	// regions (in image pixels) that are ignored entirely
+	Masks []image.Rectangle
+}
+
+type imageDiff struct {
+	// the baseline image, faded out, with differences highlighted
+	Image *image.NRGBA
+
+	// the number of pixels that were compared (i.e.: were not masked)
+	Compared int
+
+	// the number of pixels that differed
+	Mismatched int
+
+	// the number of differing pixels that were ignored as anti-aliasing
+	Antialiased int
+}
+
+// Compare two images pixel by pixel, using the perceptual color distance and anti-aliasing
+// detection described in "Measuring perceived color difference using YIQ NTSC transmission color
+// space in mobile applications" (Kotsarenko & Ramos) and "Anti-aliased Pixel and Intensity Slope
+// Detector" (Vysniauskas).  If the images are different sizes, every pixel that only exists in one
+// of them is considered mismatched.
+func diffImages(baseline image.Image, current image.Image, options *imageDiffOptions) *imageDiff {
+	img1 := toNRGBA(baseline)
+	img2 := toNRGBA(current)
+
+	w1, h1 := img1.Rect.Dx(), img1.Rect.Dy()
+	w2, h2 := img2.Rect.Dx(), img2.Rect.Dy()
+
+	// the region both images have in common
+	width, height := min(w1, w2), min(h1, h2)
+
+	diff := &imageDiff{
+		Image: image.NewNRGBA(image.Rect(0, 0, max(w1, w2), max(h1, h2))),
+	}
+
+	maxDelta := maxColorDelta * options.Threshold * options.Threshold
+
+	for y := 0; y < diff.Image.Rect.Dy(); y++ {
+		for x := 0; x < diff.Image.Rect.Dx(); x++ {
+			if isMasked(x, y, options.Masks) {
+				diff.Image.SetNRGBA(x, y, diffMaskedColor)
+				continue
+			}
+
+			diff.Compared += 1
+
+			if x >= width || y >= height {
+				diff.Mismatched += 1
+				diff.Image.SetNRGBA(x, y, diffMismatchColor)
+				continue
+			}
+
+			pos := img1.PixOffset(x, y)
+
+			if delta := colorDelta(img1, img2, pos, img2.PixOffset(x, y), false); math.Abs(delta) > maxDelta {
+				if !options.IncludeAntialiasing && (isAntialiased(img1, x, y, width, height, img2) || isAntialiased(img2, x, y, width, height, img1)) {
+					diff.Antialiased += 1
+					diff.Image.SetNRGBA(x, y, diffAntialiasedColor)
+				} else {
+					diff.Mismatched += 1
+					diff.Image.SetNRGBA(x, y, diffMismatchColor)
+				}
+			} else {
+				// draw unchanged pixels as a faded, grayscale copy of the baseline
+				r, g, b, a := pixelAt(img1, pos)
+				gray := uint8(blend(rgb2y(r, g, b), 0.1*a/255))
+
+				diff.Image.SetNRGBA(x, y, color.NRGBA{gray, gray, gray, 255})
+			}
+		}
+	}
+
+	return diff
+}
+
+// Return the percentage of compared pixels that were mismatched.
+func (self *imageDiff) MismatchPercent() float64 {
+	if self.Compared == 0 {
+		return 0
+	}
+
+	return float64(self.Mismatched) / float64(self.Compared) * 100
+}
+
+// return the image as non-premultiplied RGBA with its origin at (0, 0)
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+
+	bounds := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(out, out.Rect, img, bounds.Min, draw.Src)
+
+	return out
+}
+
+func isMasked(x int, y int, masks []image.Rectangle) bool {
+	point := image.Pt(x, y)
+
+	for _, mask := range masks {
+		if point.In(mask) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Determine whether the pixel at the given position is likely an anti-aliased edge, which is
+// the case when it has both a darker and a brighter neighbor, and either of those neighbors sits
+// in an area of flat color in both images.
+func isAntialiased(img *image.NRGBA, x1 int, y1 int, width int, height int, other *image.NRGBA) bool {
+	x0, y0 := max(x1-1, 0), max(y1-1, 0)
+	x2, y2 := min(x1+1, width-1), min(y1+1, height-1)
+	pos := img.PixOffset(x1, y1)
+	zeroes := 0
+
+	// pixels on the edge of the image have fewer neighbors
+	if x1 == x0 || x1 == x2 || y1 == y0 || y1 == y2 {
+		zeroes = 1
+	}
+
+	var darkest, brightest float64
+	var minX, minY, maxX, maxY int
+
+	for x := x0; x <= x2; x++ {
+		for y := y0; y <= y2; y++ {
+			if x == x1 && y == y1 {
+				continue
+			}
+
+			delta := colorDelta(img, img, pos, img.PixOffset(x, y), true)
+
+			if delta == 0 {
+				zeroes += 1
+
+				// more than two identical neighbors means this isn't an edge
+				if zeroes > 2 {
+					return false
+				}
+			} else if delta < darkest {
+				darkest = delta
+				minX, minY = x, y
+			} else if delta > brightest {
+				brightest = delta
+				maxX, maxY = x, y
+			}
+		}
+	}
+
+	// anti-aliased pixels have both darker and brighter neighbors
+	if darkest == 0 || brightest == 0 {
+		return false
+	}
+
+	return (hasManySiblings(img, minX, minY, width, height) && hasManySiblings(other, minX, minY, width, height)) ||
+		(hasManySiblings(img, maxX, maxY, width, height) && hasManySiblings(other, maxX, maxY, width, height))
+}
+
+// determine whether the pixel at the given position has more than two identical neighbors
+func hasManySiblings(img *image.NRGBA, x1 int, y1 int, width int, height int) bool {
+	x0, y0 := max(x1-1, 0), max(y1-1, 0)
+	x2, y2 := min(x1+1, width-1), min(y1+1, height-1)
+	pos := img.PixOffset(x1, y1)
+	zeroes := 0
+
+	if x1 == x0 || x1 == x2 || y1 == y0 || y1 == y2 {
+		zeroes = 1
+	}
+
+	for x := x0; x <= x2; x++ {
+		for y := y0; y <= y2; y++ {
+			if x == x1 && y == y1 {
+				continue
+			}
+
+			other := img.PixOffset(x, y)
+
+			if img.Pix[pos] == img.Pix[other] &&
+				img.Pix[pos+1] == img.Pix[other+1] &&
+				img.Pix[pos+2] == img.Pix[other+2] &&
+				img.Pix[pos+3] == img.Pix[other+3] {
+				zeroes += 1
+			}
+
+			if zeroes > 2 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Return the perceptual difference between two pixels.  The result is negative if the second
+// pixel is darker than the first.  If yOnly is set, only the difference in brightness is returned.
+func colorDelta(img1 *image.NRGBA, img2 *image.NRGBA, pos1 int, pos2 int, yOnly bool) float64 {
+	r1, g1, b1, a1 := pixelAt(img1, pos1)
+	r2, g2, b2, a2 := pixelAt(img2, pos2)
+
+	if r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2 {
+		return 0
+	}
+
+	// blend semi-transparent pixels with white
+	if a1 < 255 {
+		a1 /= 255
+		r1, g1, b1 = blend(r1, a1), blend(g1, a1), blend(b1, a1)
+	}
+
+	if a2 < 255 {
+		a2 /= 255
+		r2, g2, b2 = blend(r2, a2), blend(g2, a2), blend(b2, a2)
+	}
+
+	y1, y2 := rgb2y(r1, g1, b1), rgb2y(r2, g2, b2)
+	y := y1 - y2
+
+	if yOnly {
+		return y
+	}
+
+	i := rgb2i(r1, g1, b1) - rgb2i(r2, g2, b2)
+	q := rgb2q(r1, g1, b1) - rgb2q(r2, g2, b2)
+	delta := 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+
+	if y1 > y2 {
+		return -delta
+	}
+
+	return delta
+}
+
+func pixelAt(img *image.NRGBA, pos int) (float64, float64, float64, float64) {
+	return float64(img.Pix[pos]), float64(img.Pix[pos+1]), float64(img.Pix[pos+2]), float64(img.Pix[pos+3])
+}
+
+func rgb2y(r float64, g float64, b float64) float64 {
+	return r*0.29889531 + g*0.58662247 + b*0.11448223
+}
+
+func rgb2i(r float64, g float64, b float64) float64 {
+	return r*0.59597799 - g*0.27417610 - b*0.32180189
+}
+
+func rgb2q(r float64, g float64, b float64) float64 {
+	return r*0.21147017 - g*0.52261711 + b*0.31114694
+}
+
+// blend a color channel with white by the given opacity
+func blend(c float64, alpha float64) float64 {
+	return 255 + (c-255)*alpha
+}
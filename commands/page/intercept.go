@@ -129,6 +129,11 @@ func (self *Commands) Intercept(match string, args *InterceptArgs) error {
 			}
 
 			if origin := event.P().String(`authChallenge.origin`); origin != `` {
+				// leave proxy challenges to the tab's proxy credentials unless we were given some
+				if event.P().String(`authChallenge.source`) == `Proxy` && args.Username == `` && args.Password == `` {
+					return nil
+				}
+
 				if args.Realm == `` || args.Realm == event.P().String(`authChallenge.realm`) {
 					u := args.Username
 					p := args.Password
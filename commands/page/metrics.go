@@ -0,0 +1,170 @@
+package page
+
+import (
+	"fmt"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/utils"
+)
+
+type MetricsArgs struct {
+	// Load the page from scratch (with an empty cache) and collect metrics for that load, rather
+	// than collecting metrics for the page as it currently is.
+	Cold bool `json:"cold"`
+
+	// Load this URL and collect metrics for it.  If Cold is set and no URL is given, the current
+	// page is reloaded.
+	URL string `json:"url"`
+
+	// The amount of time to wait for the page to load.
+	Timeout time.Duration `json:"timeout" default:"30s"`
+
+	// How long to wait before collecting metrics, giving late-loading content and layout shifts a
+	// chance to settle.
+	Settle time.Duration `json:"settle" default:"500ms"`
+}
+
+type MetricsResponse struct {
+	// The URL of the page the metrics were collected from.
+	URL string `json:"url"`
+
+	// The browser's runtime performance metrics (e.g.: JSHeapUsedSize, LayoutCount,
+	// ScriptDuration, TaskDuration).
+	Performance map[string]float64 `json:"performance"`
+
+	// The page's Navigation Timing entry (e.g.: domInteractive, domContentLoadedEventEnd,
+	// loadEventEnd, transferSize), with times in milliseconds since the start of navigation.
+	Navigation map[string]interface{} `json:"navigation"`
+
+	// User-centric metrics (ttfb, fcp, lcp, cls, inp, fid, tbt), in milliseconds since the start of
+	// navigation.
+	*browser.WebVitals
+}
+
+// Collect performance metrics for the current page: the browser's runtime metrics, Navigation
+// Timing, and the Core Web Vitals and other user-centric metrics (Largest Contentful Paint,
+// Cumulative Layout Shift, Interaction to Next Paint, First Input Delay, and Total Blocking Time).
+//
+// Metrics that are only known after the page has loaded (or been interacted with) are most
+// accurate when page::metrics is called once before loading the page (or with the "cold" option),
+// since observers are then in place from the very start.  Interaction to Next Paint and First Input
+// Delay are only available once the page has been interacted with.
+//
+// #### Examples
+//
+// ##### Measure a cold load of a page.
+// ```
+//
+//	page::metrics {
+//	  url:  'https://example.com',
+//	  cold: true,
+//	} -> $metrics
+//
+// log "LCP: {metrics[lcp]}ms ({metrics[lcp_element]}), CLS: {metrics[cls]}, TBT: {metrics[tbt]}ms"
+// ```
+//
+// ##### Measure how responsive a page is to interaction.
+// ```
+// page::metrics
+// go 'https://example.com/search'
+// field 'input[name="q"]' { value: 'webfriend' }
+// click 'button[type="submit"]'
+// page::metrics -> $metrics
+// log "INP: {metrics[inp]}ms"
+// ```
+func (self *Commands) Metrics(args *MetricsArgs) (*MetricsResponse, error) {
+	if args == nil {
+		args = &MetricsArgs{}
+	}
+
+	defaults.SetDefaults(args)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
+	args.Settle = utils.FudgeDuration(args.Settle)
+
+	tab := self.browser.Tab()
+
+	if err := tab.EnableMetrics(); err != nil {
+		return nil, err
+	}
+
+	if args.Cold || args.URL != `` {
+		if err := self.metricsLoad(tab, args); err != nil {
+			return nil, err
+		}
+	}
+
+	if args.Settle > 0 {
+		time.Sleep(args.Settle)
+	}
+
+	response := &MetricsResponse{}
+
+	if performance, err := tab.PerformanceMetrics(); err == nil {
+		response.Performance = performance
+	} else {
+		return nil, err
+	}
+
+	if navigation, vitals, err := tab.WebVitals(); err == nil {
+		response.Navigation = navigation
+		response.WebVitals = vitals
+		response.URL = typeutil.String(navigation[`name`])
+	} else {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// load (or reload) the page, optionally with an empty cache, and wait for it to finish loading
+func (self *Commands) metricsLoad(tab *browser.Tab, args *MetricsArgs) error {
+	if args.Cold {
+		if _, err := tab.RPC(`Network`, `clearBrowserCache`, nil); err != nil {
+			return err
+		}
+
+		if _, err := tab.RPC(`Network`, `setCacheDisabled`, map[string]interface{}{
+			`cacheDisabled`: true,
+		}); err != nil {
+			return err
+		}
+
+		defer tab.RPC(`Network`, `setCacheDisabled`, map[string]interface{}{
+			`cacheDisabled`: false,
+		})
+	}
+
+	// register the waiter before loading, since fast pages may finish before we'd otherwise be listening
+	waiter, err := tab.CreateEventWaiter(`Page.loadEventFired`)
+
+	if err != nil {
+		return err
+	}
+
+	defer waiter.Remove()
+
+	if args.URL != `` {
+		_, err = tab.Navigate(args.URL)
+	} else {
+		_, err = tab.RPC(`Page`, `reload`, map[string]interface{}{
+			`ignoreCache`: args.Cold,
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := waiter.Wait(args.Timeout); err != nil {
+		if utils.IsTimeoutErr(err) {
+			return fmt.Errorf("timed out waiting for the page to load")
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -1,25 +1,118 @@
 package page
 
 import (
-	"encoding/base64"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 
 	defaults "github.com/ghetzel/go-defaults"
 	"github.com/ghetzel/go-stockutil/log"
 	"github.com/ghetzel/go-stockutil/maputil"
+	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/browser"
 )
 
 type PdfArgs struct {
 	// Whether the given destination should be automatically closed for writing after the
 	// PDF is written.
 	Autoclose bool `json:"autoclose" default:"true"`
+
+	// The paper size to use; one of "letter", "legal", "tabloid", "ledger", or "a0" through "a6".
+	Format string `json:"format" default:"letter"`
+
+	// The width of the paper, overriding the paper size given in Format.  May be a number of
+	// inches or a string with units (e.g.: "210mm", "21cm", "8.5in", "816px", "612pt").
+	Width interface{} `json:"width"`
+
+	// The height of the paper, overriding the paper size given in Format.  Accepts the same units
+	// as Width.
+	Height interface{} `json:"height"`
+
+	// Print the page in landscape orientation.
+	Landscape bool `json:"landscape"`
+
+	// The margins around each page.  May be a single length applied to all sides, or an object
+	// with any of the keys "top", "right", "bottom", and "left".  Accepts the same units as Width.
+	// The browser's default margin is used for any side that isn't given.
+	Margin interface{} `json:"margin"`
+
+	// Print background colors and images.
+	PrintBackground bool `json:"print_background"`
+
+	// The scale to render the page at (between 0.1 and 2).
+	Scale float64 `json:"scale" default:"1"`
+
+	// Which pages to print (e.g.: "1-5, 8, 11-13"); all pages are printed if not given.
+	PageRanges string `json:"page_ranges"`
+
+	// An HTML template for the header of each page.  Elements with the classes "date", "title",
+	// "url", "pageNumber", and "totalPages" are filled in with the corresponding values.
+	HeaderTemplate string `json:"header_template"`
+
+	// An HTML template for the footer of each page (see HeaderTemplate).
+	FooterTemplate string `json:"footer_template"`
+
+	// Use any paper size defined by the page's CSS @page rules instead of the given paper size.
+	PreferCSSPageSize bool `json:"prefer_css_page_size"`
+
+	// Embed a document outline (generated from the page's headings) in the PDF.
+	Outline bool `json:"outline"`
+
+	// Generate a tagged (accessible) PDF.
+	Tagged bool `json:"tagged"`
+
+	// Retrieve the PDF from the browser in chunks rather than all at once.  Recommended for very
+	// large documents.
+	Stream bool `json:"stream"`
+}
+
+type PdfResponse struct {
+	// The filesystem path that the PDF was written to.
+	Path string `json:"path,omitempty"`
+
+	// The size of the PDF (in bytes).
+	Size int64 `json:"size"`
 }
 
 // Render the current page as a PDF document, writing it to the given filename or writable
 // destination object.
-func (self *Commands) Pdf(destination interface{}, args *PdfArgs) error {
+//
+// If the filename is the string `"temporary"`, a file will be created in the system's
+// temporary area (e.g.: `/tmp`) and the PDF will be written there.  The temporary file path is
+// available in the return object's `path` parameter.
+//
+// #### Examples
+//
+// ##### Generate an A4 invoice with narrow margins and page numbers.
+// ```
+//
+//	page::pdf '/tmp/invoice.pdf' {
+//	  format:           'a4',
+//	  margin:           '10mm',
+//	  print_background: true,
+//	  footer_template:  '<div style="font-size: 8px; margin: 0 auto;"><span class="pageNumber"></span> / <span class="totalPages"></span></div>',
+//	}
+//
+// ```
+//
+// ##### Print the first two pages of a report on custom-sized paper in landscape.
+// ```
+//
+//	page::pdf '/tmp/report.pdf' {
+//	  width:       '300mm',
+//	  height:      '200mm',
+//	  landscape:   true,
+//	  page_ranges: '1-2',
+//	  margin: {
+//	    top:    '1in',
+//	    bottom: '1in',
+//	  },
+//	}
+//
+// ```
+func (self *Commands) Pdf(destination interface{}, args *PdfArgs) (*PdfResponse, error) {
 	var dest io.Writer
 
 	if args == nil {
@@ -27,51 +120,130 @@ func (self *Commands) Pdf(destination interface{}, args *PdfArgs) error {
 	}
 
 	defaults.SetDefaults(args)
+	response := &PdfResponse{}
 
 	switch destination.(type) {
 	case string:
 		filename := destination.(string)
 
-		if _, w, err := self.browser.GetWriterForPath(filename); err == nil {
+		if newPath, w, err := self.browser.GetWriterForPath(filename); err == nil && w != nil {
 			dest = w
+			response.Path = newPath
+		} else if filename == `temporary` {
+			if temp, err := ioutil.TempFile(``, `*.pdf`); err == nil {
+				dest = temp
+				response.Path = temp.Name()
+			} else {
+				return nil, err
+			}
 		} else if d, err := os.Create(filename); err == nil {
 			dest = d
+			response.Path = filename
 		} else {
-			return err
+			return nil, err
 		}
 	case io.Writer:
 		dest = destination.(io.Writer)
 	default:
-		return fmt.Errorf("Must specify either a filename or io.Writer destination")
+		return nil, fmt.Errorf("Must specify either a filename or io.Writer destination")
 	}
 
 	if dest == nil {
-		return fmt.Errorf("A destination for the PDF must be specified")
+		return nil, fmt.Errorf("A destination for the PDF must be specified")
+	}
+
+	options, err := pdfOptions(args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n, err := self.browser.Tab().PrintToPDF(options, dest); err == nil {
+		response.Size = n
+	} else {
+		return nil, err
 	}
 
-	if rv, err := self.browser.Tab().RPC(`Page`, `printToPDF`, map[string]interface{}{
-		`scale`: 1,
-	}); err == nil {
-		if dataS := maputil.M(rv.Result).String(`data`); len(dataS) > 0 {
-			if data, err := base64.StdEncoding.DecodeString(dataS); err == nil {
-				_, err := dest.Write(data)
+	if args.Autoclose {
+		if closer, ok := dest.(io.Closer); ok {
+			if err := closer.Close(); err == nil {
+				log.Debugf("Destination file closed.")
+			} else {
+				return response, err
+			}
+		}
+	}
 
-				if closer, ok := dest.(io.Closer); ok {
-					if err := closer.Close(); err == nil {
-						log.Debugf("Destination file closed.")
+	return response, nil
+}
+
+// convert the command arguments into options for printing the page
+func pdfOptions(args *PdfArgs) (*browser.PdfOptions, error) {
+	options := &browser.PdfOptions{
+		Landscape:           args.Landscape,
+		PrintBackground:     args.PrintBackground,
+		Scale:               args.Scale,
+		PageRanges:          args.PageRanges,
+		DisplayHeaderFooter: (args.HeaderTemplate != `` || args.FooterTemplate != ``),
+		HeaderTemplate:      args.HeaderTemplate,
+		FooterTemplate:      args.FooterTemplate,
+		PreferCSSPageSize:   args.PreferCSSPageSize,
+		Outline:             args.Outline,
+		Tagged:              args.Tagged,
+		Stream:              args.Stream,
+	}
+
+	if size, ok := browser.PaperSizes[strings.ToLower(args.Format)]; ok {
+		options.Width, options.Height = size[0], size[1]
+	} else {
+		return nil, fmt.Errorf("Unsupported paper size %q", args.Format)
+	}
+
+	if args.Width != nil {
+		if width, err := browser.ParsePaperLength(args.Width); err == nil {
+			options.Width = width
+		} else {
+			return nil, fmt.Errorf("width: %v", err)
+		}
+	}
+
+	if args.Height != nil {
+		if height, err := browser.ParsePaperLength(args.Height); err == nil {
+			options.Height = height
+		} else {
+			return nil, fmt.Errorf("height: %v", err)
+		}
+	}
+
+	if args.Margin != nil {
+		options.Margins = &browser.PdfMargins{}
+
+		if typeutil.IsMap(args.Margin) {
+			margins := maputil.M(args.Margin)
+
+			for side, value := range map[string]**float64{
+				`top`:    &options.Margins.Top,
+				`right`:  &options.Margins.Right,
+				`bottom`: &options.Margins.Bottom,
+				`left`:   &options.Margins.Left,
+			} {
+				if v := margins.Get(side).Value; v != nil {
+					if length, err := browser.ParsePaperLength(v); err == nil {
+						*value = &length
 					} else {
-						return err
+						return nil, fmt.Errorf("margin %s: %v", side, err)
 					}
 				}
-
-				return err
-			} else {
-				return fmt.Errorf("decode error: %v", err)
 			}
+		} else if length, err := browser.ParsePaperLength(args.Margin); err == nil {
+			options.Margins.Top = &length
+			options.Margins.Right = &length
+			options.Margins.Bottom = &length
+			options.Margins.Left = &length
 		} else {
-			return fmt.Errorf("Empty response")
+			return nil, fmt.Errorf("margin: %v", err)
 		}
-	} else {
-		return err
 	}
+
+	return options, nil
 }
@@ -0,0 +1,198 @@
+package page
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-stockutil/pathutil"
+	"github.com/ghetzel/go-webfriend/browser"
+)
+
+type RecordStartArgs struct {
+	// The maximum width of the recorded frames; larger frames are scaled down to fit.
+	MaxWidth int `json:"max_width"`
+
+	// The maximum height of the recorded frames; larger frames are scaled down to fit.
+	MaxHeight int `json:"max_height"`
+
+	// Only record every Nth frame the browser renders.
+	EveryNthFrame int `json:"every_nth_frame" default:"1"`
+
+	// Stop recording new frames once this many have been recorded.  Recorded frames are held in
+	// memory until the recording is stopped, so there is a limit by default; a negative value
+	// means no limit.
+	MaxFrames int `json:"max_frames" default:"1000"`
+}
+
+// Start recording everything rendered in the current tab.  Every frame is captured along with when
+// it was rendered, so that it can be played back at the speed it happened.  Use record_stop to
+// stop recording and save the result.
+//
+// If the tab is already being screencast (e.g.: to the debugging UI), the recording uses that
+// screencast's frames, and the max_width, max_height, and every_nth_frame options cannot be given.
+//
+// #### Examples
+//
+// ##### Record a checkout flow as an animated GIF.
+// ```
+// page::record_start { max_width: 800 }
+// go 'https://example.com/cart'
+// click '#checkout'
+// page::record_stop '/tmp/checkout.gif'
+// ```
+func (self *Commands) RecordStart(args *RecordStartArgs) error {
+	if args == nil {
+		args = &RecordStartArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	if args.MaxFrames < 0 {
+		args.MaxFrames = 0
+	}
+
+	return self.browser.Tab().StartRecording(&browser.RecordingOptions{
+		MaxWidth:      args.MaxWidth,
+		MaxHeight:     args.MaxHeight,
+		EveryNthFrame: args.EveryNthFrame,
+		MaxFrames:     args.MaxFrames,
+	})
+}
+
+type RecordStopArgs struct {
+	// The format to save the recording in; one of "gif", "apng", or "png" (a numbered sequence of
+	// PNG images and a "manifest.json" file describing their timing).  If not given, it is
+	// determined from the destination: a ".gif" or ".apng"/".png" file, or otherwise a directory.
+	Format string `json:"format"`
+
+	// How many times the animation should play; 0 means forever.  Does not apply to image
+	// sequences.
+	Loop int `json:"loop"`
+}
+
+type RecordStopResponse struct {
+	// The file (or directory, for image sequences) the recording was written to.
+	Path string `json:"path,omitempty"`
+
+	// The format the recording was saved in.
+	Format string `json:"format,omitempty"`
+
+	// The number of frames that were recorded.
+	Frames int `json:"frames"`
+
+	// How long the recording ran for.
+	Duration time.Duration `json:"duration"`
+
+	// The size of the saved animation (in bytes).
+	Size int64 `json:"size,omitempty"`
+
+	// For image sequences, the timing of each frame.
+	Manifest *browser.RecordingManifest `json:"manifest,omitempty"`
+}
+
+// Stop recording the current tab, saving the recording to the given destination as an animated
+// GIF, an animated PNG, or a numbered sequence of PNG images.  If no destination is given, the
+// recording is discarded.
+//
+// #### Examples
+//
+// ##### Save a recording as a lossless animated PNG, playing it once.
+// ```
+//
+//	page::record_stop '/tmp/run.apng' {
+//	  loop: 1,
+//	}
+//
+// ```
+//
+// ##### Save every frame as a separate image for later inspection.
+// ```
+// page::record_stop '/tmp/run-frames/' -> $recording
+// log "Recorded {recording[frames]} frames"
+// ```
+func (self *Commands) RecordStop(destination string, args *RecordStopArgs) (*RecordStopResponse, error) {
+	if args == nil {
+		args = &RecordStopArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	recording, err := self.browser.Tab().StopRecording()
+
+	if err != nil {
+		return nil, err
+	}
+
+	response := &RecordStopResponse{
+		Frames:   len(recording.Frames),
+		Duration: recording.Duration(),
+	}
+
+	if destination == `` {
+		return response, nil
+	} else if expanded, err := pathutil.ExpandUser(destination); err == nil {
+		destination = expanded
+	} else {
+		return nil, err
+	}
+
+	response.Path = destination
+	response.Format = strings.ToLower(args.Format)
+
+	if response.Format == `` {
+		switch strings.ToLower(filepath.Ext(destination)) {
+		case `.gif`:
+			response.Format = `gif`
+		case `.apng`, `.png`:
+			response.Format = `apng`
+		default:
+			response.Format = `png`
+		}
+	}
+
+	switch response.Format {
+	case `png`:
+		if manifest, err := recording.WriteSequence(destination); err == nil {
+			response.Manifest = manifest
+			return response, nil
+		} else {
+			return nil, err
+		}
+	case `gif`, `apng`:
+		if dir := filepath.Dir(destination); dir != `` {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, err
+			}
+		}
+
+		file, err := os.Create(destination)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer file.Close()
+
+		if response.Format == `gif` {
+			err = recording.EncodeGIF(file, args.Loop)
+		} else {
+			err = recording.EncodeAPNG(file, args.Loop)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if stat, err := file.Stat(); err == nil {
+			response.Size = stat.Size()
+		}
+
+		return response, nil
+	default:
+		return nil, fmt.Errorf("Unsupported recording format %q", args.Format)
+	}
+}
@@ -2,14 +2,15 @@ package page
 
 import (
 	"bytes"
-	"encoding/base64"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 
 	defaults "github.com/ghetzel/go-defaults"
 	"github.com/ghetzel/go-stockutil/log"
+	"github.com/ghetzel/go-webfriend/browser"
 	"github.com/ghetzel/go-webfriend/dom"
 )
 
@@ -21,39 +22,61 @@ type ScreenshotArgs struct {
 	// May be "tallest" or "first".
 	Use string `json:"use,omitempty" default:"tallest"`
 
-	Width  int `json:"width"`
+	// The width of the region to capture (defaults to the rest of the page's width).
+	Width int `json:"width"`
+
+	// The height of the region to capture (defaults to the rest of the page's height).
 	Height int `json:"height"`
-	X      int `json:"x" default:"-1"`
-	Y      int `json:"y" default:"-1"`
 
-	// The output image format of the screenshot.  May be "png" or "jpeg".
+	// The horizontal position (relative to the top-left corner of the page) of the region to capture.
+	X int `json:"x" default:"-1"`
+
+	// The vertical position (relative to the top-left corner of the page) of the region to capture.
+	Y int `json:"y" default:"-1"`
+
+	// The output image format of the screenshot.  May be "png", "jpeg", or "webp".
 	Format string `json:"format" default:"png"`
 
-	// The quality of the image used during encoding.  Only applies to "jpeg" format.
+	// The quality of the image used during encoding.  Only applies to "jpeg" and "webp" formats.
 	Quality int `json:"quality"`
 
 	// Whether the given destination should be automatically closed for writing after the
 	// screenshot is written.
 	Autoclose bool `json:"autoclose" default:"true"`
 
-	// Automatically resize the screen to the width and height.
-	Autoresize bool `json:"autoresize" default:"true"`
+	// Resize the viewport to the given width and height and capture what is visible, rather than
+	// capturing a region of the page.  The viewport is restored afterwards.
+	Autoresize bool `json:"autoresize"`
+
+	// Capture only the visible part of the page (rather than the entire page) when no selector or
+	// region is given.
+	ViewportOnly bool `json:"viewport_only"`
+
+	// The device scale factor of the screenshot (e.g.: 2 to capture at twice the resolution).
+	Scale float64 `json:"scale" default:"1"`
+
+	// Render the page with a transparent background instead of white.  Only applies to the "png"
+	// and "webp" formats.
+	OmitBackground bool `json:"omit_background"`
+
+	// When capturing an element, include this many pixels of the page around it.
+	Padding int `json:"padding"`
 }
 
 type ScreenshotResponse struct {
 	// Details about the element that matched the given selector (if any).
 	Element *dom.Element `json:"element,omitempty"`
 
-	// The width of the screenshot (in pixels).
+	// The width of the screenshot (in CSS pixels).
 	Width int `json:"width"`
 
-	// The height of the screenshot (in pixels).
+	// The height of the screenshot (in CSS pixels).
 	Height int `json:"height"`
 
-	// The X position (relative to the viewport) the screenshot was taken at.
+	// The X position (relative to the top-left corner of the page) the screenshot was taken at.
 	X int `json:"x"`
 
-	// The Y position (relative to the viewport) the screenshot was taken at.
+	// The Y position (relative to the top-left corner of the page) the screenshot was taken at.
 	Y int `json:"y"`
 
 	// The filesystem path that the screenshot was written to.
@@ -63,13 +86,52 @@ type ScreenshotResponse struct {
 	Size int64 `json:"size,omitempty"`
 }
 
-// Render the current page as a PNG or JPEG image, writing it to the given filename or writable
-// destination object.
+// Render the current page as a PNG, JPEG, or WebP image, writing it to the given filename or
+// writable destination object.  By default the whole page is captured, without resizing the
+// viewport (so sticky headers and viewport-relative layouts render as they would for a user).
+// Pages taller than the browser can render in one piece are captured in tiles and stitched
+// together.
 //
 // If the filename is the string `"temporary"`, a file will be created in the system's
 // temporary area (e.g.: `/tmp`) and the screenshot will be written there.  It is the caller's
 // responsibility to remove the temporary file if desired.  The temporary file path is available in
 // the return object's `path` parameter.
+//
+// #### Examples
+//
+// ##### Capture a high-resolution image of a chart, with some space around it.
+// ```
+//
+//	page::screenshot '/tmp/chart.png' {
+//	  selector: '#revenue-chart',
+//	  scale:    2,
+//	  padding:  16,
+//	}
+//
+// ```
+//
+// ##### Capture a logo with a transparent background.
+// ```
+//
+//	page::screenshot '/tmp/logo.webp' {
+//	  selector:        'header .logo',
+//	  format:          'webp',
+//	  omit_background: true,
+//	}
+//
+// ```
+//
+// ##### Capture a fixed region of the page.
+// ```
+//
+//	page::screenshot '/tmp/banner.png' {
+//	  x:      0,
+//	  y:      120,
+//	  width:  1024,
+//	  height: 300,
+//	}
+//
+// ```
 func (self *Commands) Screenshot(destination interface{}, args *ScreenshotArgs) (*ScreenshotResponse, error) {
 	if args == nil {
 		args = &ScreenshotArgs{}
@@ -82,7 +144,7 @@ func (self *Commands) Screenshot(destination interface{}, args *ScreenshotArgs)
 
 	if destination != nil {
 		if filename, ok := destination.(string); ok {
-			if newPath, w, err := self.browser.GetWriterForPath(filename); err == nil {
+			if newPath, w, err := self.browser.GetWriterForPath(filename); err == nil && w != nil {
 				writer = w
 				response.Path = newPath
 			} else if filename == `temporary` {
@@ -109,154 +171,180 @@ func (self *Commands) Screenshot(destination interface{}, args *ScreenshotArgs)
 		return response, fmt.Errorf("A destination for the screenshot must be specified")
 	}
 
-	// if one or both of the dimensions are not explicitly given, fill them in from the current
-	// page dimensions (scroll width/height)
-	if args.Width == 0 || args.Height == 0 {
-		if pageWidth, pageHeight, err := self.browser.Tab().PageSize(); err == nil {
-			if args.Width == 0 {
-				args.Width = int(pageWidth)
-			}
+	if data, err := self.captureScreenshot(args, response); err == nil {
+		if n, err := io.Copy(writer, bytes.NewReader(data)); err == nil {
+			response.Size = n
 
-			if args.Height == 0 {
-				args.Height = int(pageHeight)
+			if args.Autoclose {
+				if closer, ok := writer.(io.Closer); ok {
+					if err := closer.Close(); err == nil {
+						log.Debugf("Destination file closed.")
+					} else {
+						return response, err
+					}
+				}
 			}
-		}
-	}
 
-	if err := self.screenshotPopulateArgsFromElement(args, response); err != nil {
+			return response, nil
+		} else {
+			return response, fmt.Errorf("Error writing screenshot: %v", err)
+		}
+	} else {
 		return response, err
 	}
+}
 
-	// start building screenshot RPC request
-	screenshot := map[string]interface{}{
-		`format`:      args.Format,
-		`fromSurface`: false,
+// capture the screenshot described by the given arguments, populating the response with details
+// about what was captured
+func (self *Commands) captureScreenshot(args *ScreenshotArgs, response *ScreenshotResponse) ([]byte, error) {
+	tab := self.browser.Tab()
+	options := &browser.ScreenshotOptions{
+		Format:         args.Format,
+		Quality:        args.Quality,
+		FullPage:       !args.ViewportOnly,
+		Scale:          args.Scale,
+		OmitBackground: args.OmitBackground,
 	}
 
-	// set quality for format="jpeg"
-	if args.Format == `jpeg` && args.Quality > 0 {
-		screenshot[`quality`] = args.Quality
-	}
+	if args.Autoresize && args.Width > 0 && args.Height > 0 {
+		// resize viewport to given width and height, putting it back the way it was afterwards
+		previous := tab.DeviceMetrics()
 
-	// setup clipping region to the given X/Y if specified
-	if args.X > 0 || args.Y > 0 {
-		screenshot[`clip`] = map[string]interface{}{
-			`width`:  args.Width,
-			`height`: args.Height,
-			`x`:      args.X,
-			`y`:      args.Y,
-			`scale`:  1.0,
-		}
-	} else if args.Autoresize && args.Width > 0 && args.Height > 0 {
-		// resize viewport to given width and height
-		if _, err := self.browser.Tab().RPC(`Emulation`, `setDeviceMetricsOverride`, map[string]interface{}{
+		if err := tab.SetDeviceMetrics(map[string]interface{}{
 			`width`:             args.Width,
 			`height`:            args.Height,
-			`deviceScaleFactor`: 1.0,
+			`deviceScaleFactor`: 0,
 			`mobile`:            false,
 		}); err != nil {
-			return response, fmt.Errorf("Failed to resize screen: %v", err)
+			return nil, fmt.Errorf("Failed to resize screen: %v", err)
 		}
+
+		defer tab.SetDeviceMetrics(previous)
+		options.FullPage = false
+	} else if clip, err := self.screenshotClip(args, response); err == nil {
+		options.Clip = clip
+	} else {
+		return nil, err
 	}
 
-	// take the screenshot
-	if reply, err := self.browser.Tab().RPC(`Page`, `captureScreenshot`, screenshot); err == nil {
-		defer func() {
-			self.browser.Tab().RPC(`Emulation`, `clearDeviceMetricsOverride`, nil)
-			self.browser.Tab().RPC(`Emulation`, `resetPageScaleFactor`, nil)
-		}()
-
-		response.Width = args.Width
-		response.Height = args.Height
-		response.X = args.X
-		response.Y = args.Y
-
-		// decode the response from base64, write the data to destination
-		if data := reply.R().String(`data`); data != `` {
-			decoder := base64.NewDecoder(base64.StdEncoding, bytes.NewBufferString(data))
-
-			if n, err := io.Copy(writer, decoder); err == nil {
-				response.Size = n
-
-				if args.Autoclose {
-					if closer, ok := writer.(io.Closer); ok {
-						if err := closer.Close(); err == nil {
-							log.Debugf("Destination file closed.")
-						} else {
-							return response, err
-						}
-					}
-				}
+	if data, region, err := tab.CaptureScreenshot(options); err == nil {
+		response.X = int(region.X)
+		response.Y = int(region.Y)
+		response.Width = int(region.Width)
+		response.Height = int(region.Height)
 
-				return response, nil
-			} else {
-				return response, fmt.Errorf("Error decoding screenshot response: %v", err)
-			}
-		} else {
-			return response, fmt.Errorf("Empty response received while capturing screenshot")
-		}
+		return data, nil
 	} else {
-		return response, err
+		return nil, err
 	}
 }
 
-func (self *Commands) screenshotPopulateArgsFromElement(args *ScreenshotArgs, response *ScreenshotResponse) error {
+// determine the region of the page (if any) that should be captured
+func (self *Commands) screenshotClip(args *ScreenshotArgs, response *ScreenshotResponse) (*browser.Rect, error) {
+	tab := self.browser.Tab()
+
+	viewport, content, err := tab.LayoutMetrics()
+
+	if err != nil {
+		return nil, err
+	}
+
 	// if screenshotting an element, find that element now
 	if args.Selector != `` {
-		if elements, err := self.browser.Tab().ElementQuery(args.Selector, nil); err == nil {
-			var winner *dom.Dimensions
-
-			for _, element := range elements {
-				if winner == nil {
-					if winnerD, err := self.browser.Tab().ElementPosition(element); err == nil {
-						winner = &winnerD
-						response.Element = element
-					} else {
-						return fmt.Errorf("Could not determine element dimensions: %v", err)
-					}
-				} else {
-					switch args.Use {
-					case `first`:
-						break
-					case `tallest`:
-						if elementD, err := self.browser.Tab().ElementPosition(element); err == nil {
-							if elementD.Height > winner.Height {
-								winner = &elementD
-								response.Element = element
-								continue
-							}
-						} else {
-							return fmt.Errorf("Could not determine element dimensions: %v", err)
-						}
-					default:
-						return fmt.Errorf("Unsupported argument for 'use': %q", args.Use)
-					}
-				}
+		if box, err := self.screenshotElementBox(args, response); err == nil {
+			// element boxes are relative to the viewport, but the clip is relative to the page
+			padding := float64(args.Padding)
+			left := float64(box.Left) + viewport.X
+			top := float64(box.Top) + viewport.Y
+			right := left + float64(box.Width) + padding
+			bottom := top + float64(box.Height) + padding
+
+			// padding that would extend past the top or left of the page is dropped, rather than
+			// shifting the element off-center
+			clip := &browser.Rect{
+				X: math.Max(left-padding, 0),
+				Y: math.Max(top-padding, 0),
 			}
 
-			if winner != nil {
-				if args.Width == 0 {
-					args.Width = winner.Width
-				}
+			clip.Width = right - clip.X
+			clip.Height = bottom - clip.Y
 
-				if args.Height == 0 {
-					args.Height = winner.Height
-				}
+			return clip, nil
+		} else {
+			return nil, err
+		}
+	}
 
-				if args.X < 0 {
-					args.X = winner.Left
-				}
+	// an explicit region was given
+	if args.X >= 0 || args.Y >= 0 || args.Width > 0 || args.Height > 0 {
+		clip := &browser.Rect{
+			X:      math.Max(float64(args.X), 0),
+			Y:      math.Max(float64(args.Y), 0),
+			Width:  float64(args.Width),
+			Height: float64(args.Height),
+		}
 
-				if args.Y < 0 {
-					args.Y = winner.Top
+		if clip.Width <= 0 {
+			clip.Width = content.Width - clip.X
+		}
+
+		if clip.Height <= 0 {
+			clip.Height = content.Height - clip.Y
+		}
+
+		return clip, nil
+	}
+
+	return nil, nil
+}
+
+func (self *Commands) screenshotElementBox(args *ScreenshotArgs, response *ScreenshotResponse) (*dom.Dimensions, error) {
+	if elements, err := self.browser.Tab().ElementQuery(args.Selector, nil); err == nil {
+		var winner *dom.Dimensions
+
+		for _, element := range elements {
+			if winner == nil {
+				if winnerD, err := self.elementPosition(element); err == nil {
+					winner = &winnerD
+					response.Element = element
+				} else {
+					return nil, fmt.Errorf("Could not determine element dimensions: %v", err)
 				}
 			} else {
-				return fmt.Errorf("No element found")
+				switch args.Use {
+				case `first`:
+					break
+				case `tallest`:
+					if elementD, err := self.elementPosition(element); err == nil {
+						if elementD.Height > winner.Height {
+							winner = &elementD
+							response.Element = element
+							continue
+						}
+					} else {
+						return nil, fmt.Errorf("Could not determine element dimensions: %v", err)
+					}
+				default:
+					return nil, fmt.Errorf("Unsupported argument for 'use': %q", args.Use)
+				}
 			}
-		} else {
-			return fmt.Errorf("failed to take screenshot of element: %v", err)
 		}
+
+		if winner == nil {
+			return nil, fmt.Errorf("No element found")
+		}
+
+		return winner, nil
+	} else {
+		return nil, fmt.Errorf("failed to take screenshot of element: %v", err)
 	}
+}
 
-	return nil
+// use the bounding box retrieved when the element was selected, falling back to querying for it
+func (self *Commands) elementPosition(element *dom.Element) (dom.Dimensions, error) {
+	if element.Box != nil {
+		return *element.Box, nil
+	} else {
+		return self.browser.Tab().ElementPosition(element)
+	}
 }
@@ -0,0 +1,302 @@
+package page
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/utils"
+	"github.com/gobwas/glob"
+)
+
+type Socket struct {
+	// The unique ID of the socket connection.
+	ID string `json:"id"`
+
+	// The type of connection; either "websocket" or "eventsource".
+	Type string `json:"type"`
+
+	// The URL the socket is connected to.
+	URL string `json:"url"`
+
+	// The HTTP status of the WebSocket handshake response (if received).
+	Status int `json:"status,omitempty"`
+
+	// Whether the connection is still open.
+	Open bool `json:"open"`
+
+	// The last error reported on the connection (if any).
+	Error string `json:"error,omitempty"`
+
+	// The number of frames sent by the page.
+	FramesSent int `json:"frames_sent"`
+
+	// The number of frames received by the page.
+	FramesReceived int `json:"frames_received"`
+
+	// When the connection was first seen.
+	CreatedAt time.Time `json:"created_at"`
+
+	// When the connection was closed (if it has been).
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+}
+
+func socketFromBrowser(socket *browser.Socket) *Socket {
+	out := &Socket{
+		ID:        socket.ID,
+		Type:      string(socket.Type),
+		URL:       socket.URL,
+		Status:    socket.Status,
+		Open:      socket.IsOpen(),
+		Error:     socket.Error,
+		CreatedAt: socket.CreatedAt,
+	}
+
+	if !socket.IsOpen() {
+		closedAt := socket.ClosedAt
+		out.ClosedAt = &closedAt
+	}
+
+	for _, frame := range socket.Frames() {
+		if frame.Direction == browser.FrameSent {
+			out.FramesSent += 1
+		} else {
+			out.FramesReceived += 1
+		}
+	}
+
+	return out
+}
+
+type SocketsArgs struct {
+	// Only return sockets whose URL matches this pattern.
+	URL string `json:"url"`
+
+	// Only return sockets of this type ("websocket" or "eventsource").
+	Type string `json:"type"`
+
+	// Only return sockets that are still open, leaving out those that have already been closed.
+	OpenOnly bool `json:"open_only"`
+}
+
+// List all WebSocket and EventSource connections that the current page has opened.
+//
+// #### Examples
+//
+// ##### List all open WebSockets.
+// ```
+//
+//	page::sockets {
+//	  type:      'websocket',
+//	  open_only: true,
+//	} -> $sockets
+//
+// ```
+func (self *Commands) Sockets(args *SocketsArgs) ([]*Socket, error) {
+	if args == nil {
+		args = &SocketsArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	var sockets = make([]*Socket, 0)
+
+	if matching, err := self.findSockets(args.URL); err == nil {
+		for _, socket := range matching {
+			if args.Type != `` && string(socket.Type) != args.Type {
+				continue
+			}
+
+			if args.OpenOnly && !socket.IsOpen() {
+				continue
+			}
+
+			sockets = append(sockets, socketFromBrowser(socket))
+		}
+	} else {
+		return nil, err
+	}
+
+	return sockets, nil
+}
+
+type SocketFramesArgs struct {
+	// Only return frames going in this direction ("sent", "received"); if empty, frames in both directions are returned.
+	Direction string `json:"direction"`
+
+	// If provided, this represents a regular expression that the frame payload must match.
+	Match string `json:"match"`
+
+	// Only return this many of the most recent frames (0 returns all of them).
+	Limit int `json:"limit"`
+}
+
+// Return the frames captured on sockets whose ID or URL matches the given pattern.  If
+// the pattern is empty, frames from all sockets are returned in the order they were captured.
+//
+// #### Examples
+//
+// ##### Retrieve the last 10 messages received from the live data feed.
+// ```
+//
+//	page::socket_frames 'wss://*/live' {
+//	  direction: 'received',
+//	  limit:     10,
+//	} -> $frames
+//
+// ```
+func (self *Commands) SocketFrames(socket string, args *SocketFramesArgs) ([]*browser.SocketFrame, error) {
+	if args == nil {
+		args = &SocketFramesArgs{}
+	}
+
+	defaults.SetDefaults(args)
+
+	if matcher, err := newFrameMatcher(args.Direction, args.Match); err == nil {
+		if sockets, err := self.findSockets(socket); err == nil {
+			frames := make([]*browser.SocketFrame, 0)
+
+			for _, socket := range sockets {
+				for _, frame := range socket.Frames() {
+					if matcher(frame) {
+						frames = append(frames, frame)
+					}
+				}
+			}
+
+			if args.Limit > 0 && len(frames) > args.Limit {
+				frames = frames[len(frames)-args.Limit:]
+			}
+
+			return frames, nil
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+type WaitForFrameArgs struct {
+	// Only consider frames on sockets whose ID or URL matches this pattern.
+	Socket string `json:"socket"`
+
+	// Only consider frames going in this direction ("sent", "received"); if empty, frames in both directions are considered.
+	Direction string `json:"direction"`
+
+	// Whether frames that were captured before this command was called should be considered.
+	IncludeExisting bool `json:"include_existing"`
+
+	// The timeout before we stop waiting for a matching frame.
+	Timeout time.Duration `json:"timeout" default:"30s"`
+
+	// The polling interval between frame re-checks.
+	Interval time.Duration `json:"interval" default:"50ms"`
+}
+
+// Wait for a WebSocket or EventSource frame whose payload matches the given regular expression,
+// and return that frame.  If the pattern is empty, the next frame is returned.
+//
+// #### Examples
+//
+// ##### Wait for a price update to arrive on any socket.
+// ```
+//
+//	page::wait_for_frame '"type":\s*"price"' {
+//	  timeout: '10s',
+//	} -> $frame
+//
+// ```
+func (self *Commands) WaitForFrame(pattern string, args *WaitForFrameArgs) (*browser.SocketFrame, error) {
+	if args == nil {
+		args = &WaitForFrameArgs{}
+	}
+
+	defaults.SetDefaults(args)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
+	args.Interval = utils.FudgeDuration(args.Interval)
+
+	if matcher, err := newFrameMatcher(args.Direction, pattern); err == nil {
+		started := time.Now()
+
+		for time.Since(started) <= args.Timeout {
+			if sockets, err := self.findSockets(args.Socket); err == nil {
+				for _, socket := range sockets {
+					for _, frame := range socket.Frames() {
+						if !args.IncludeExisting && frame.Timestamp.Before(started) {
+							continue
+						}
+
+						if matcher(frame) {
+							return frame, nil
+						}
+					}
+				}
+			} else {
+				return nil, err
+			}
+
+			time.Sleep(args.Interval)
+		}
+
+		return nil, fmt.Errorf("Timed out waiting for a socket frame matching %q", pattern)
+	} else {
+		return nil, err
+	}
+}
+
+// retrieve all sockets whose ID matches exactly or whose URL matches the given glob pattern
+func (self *Commands) findSockets(pattern string) ([]*browser.Socket, error) {
+	sockets := self.browser.Tab().Sockets()
+
+	if pattern == `` {
+		return sockets, nil
+	} else if socket, ok := self.browser.Tab().GetSocket(pattern); ok {
+		return []*browser.Socket{socket}, nil
+	} else if urlPattern, err := glob.Compile(pattern); err == nil {
+		matching := make([]*browser.Socket, 0)
+
+		for _, socket := range sockets {
+			if urlPattern.Match(socket.URL) {
+				matching = append(matching, socket)
+			}
+		}
+
+		return matching, nil
+	} else {
+		return nil, fmt.Errorf("invalid socket pattern: %v", err)
+	}
+}
+
+func newFrameMatcher(direction string, pattern string) (func(*browser.SocketFrame) bool, error) {
+	var rx *regexp.Regexp
+
+	switch direction {
+	case ``, string(browser.FrameSent), browser.FrameReceived:
+		break
+	default:
+		return nil, fmt.Errorf("Unsupported frame direction %q", direction)
+	}
+
+	if pattern != `` {
+		if r, err := regexp.Compile(pattern); err == nil {
+			rx = r
+		} else {
+			return nil, fmt.Errorf("match: %v", err)
+		}
+	}
+
+	return func(frame *browser.SocketFrame) bool {
+		if direction != `` && string(frame.Direction) != direction {
+			return false
+		}
+
+		if rx != nil && !rx.Match(frame.Data()) {
+			return false
+		}
+
+		return true
+	}, nil
+}
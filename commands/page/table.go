@@ -0,0 +1,311 @@
+package page
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	defaults "github.com/ghetzel/go-defaults"
+	"github.com/ghetzel/go-stockutil/typeutil"
+	"github.com/ghetzel/go-webfriend/browser"
+	"github.com/ghetzel/go-webfriend/dom"
+	"github.com/ghetzel/go-webfriend/utils"
+)
+
+type TableArgs struct {
+	// The number of rows at the top of the table that contain column headers.  If zero, header
+	// rows are detected automatically from <thead> and <th> elements.
+	HeaderRows int `json:"header_rows"`
+
+	// Use these column names instead of the ones in the table's header rows.
+	Headers []string `json:"headers"`
+
+	// A selector matching the "next page" link or button of a paginated table.  If given, the
+	// link will be clicked and the rows of each subsequent page will be appended to the results.
+	Next dom.Selector `json:"next"`
+
+	// The maximum number of pages to read when following the next page link.
+	MaxPages int `json:"max_pages" default:"100"`
+
+	// How long to wait for the table to change after clicking the next page link.  If it doesn't
+	// change and the link is still present and enabled, an error is returned.
+	Timeout time.Duration `json:"timeout" default:"10s"`
+
+	// The polling interval between table re-checks.
+	Interval time.Duration `json:"interval" default:"125ms"`
+
+	// If specified, the rows will also be written to this path.
+	Destination string `json:"destination"`
+
+	// The format to write rows to the destination in ("csv" or "json").  If omitted, the format
+	// is determined from the destination's file extension, defaulting to JSON.
+	Format string `json:"format"`
+}
+
+// Read the contents of an HTML table into a list of rows, each of which is an object keyed by
+// column name.  Cells spanning multiple rows or columns are repeated in each row and column they
+// cover, and multi-row headers are combined into a single name (e.g.: "2019 / Revenue").  Tables
+// spanning several pages can be read by specifying a selector for the "next page" link.
+//
+// #### Examples
+//
+// ##### Read a report table and save it as a CSV file.
+// ```
+//
+//	page::table '#report' {
+//	  destination: '/data/report.csv',
+//	} -> $rows
+//
+// ```
+//
+// ##### Read every page of a paginated table.
+// ```
+//
+//	page::table 'table.results' {
+//	  next:      'a.pagination-next',
+//	  max_pages: 20,
+//	} -> $rows
+//
+// ```
+func (self *Commands) Table(selector dom.Selector, args *TableArgs) ([]map[string]interface{}, error) {
+	if args == nil {
+		args = &TableArgs{}
+	}
+
+	defaults.SetDefaults(args)
+	args.Timeout = utils.FudgeDuration(args.Timeout)
+	args.Interval = utils.FudgeDuration(args.Interval)
+
+	if selector.IsNone() {
+		selector = dom.Selector(`table`)
+	}
+
+	var result *browser.Table
+	var current *browser.Table
+	var err error
+
+	if current, err = self.readTable(selector, args); err == nil {
+		result = current
+	} else {
+		return nil, err
+	}
+
+	for page := 1; !args.Next.IsNone() && page < args.MaxPages; page++ {
+		if clicked, err := self.clickNextPage(args.Next); err != nil {
+			return nil, err
+		} else if !clicked {
+			break
+		}
+
+		if next, err := self.waitForNextPage(selector, current, args); err == nil {
+			if next == nil {
+				break
+			}
+
+			result.Rows = append(result.Rows, next.Rows...)
+			current = next
+		} else {
+			return nil, err
+		}
+	}
+
+	if len(args.Headers) > 0 {
+		result.Headers = args.Headers
+	}
+
+	if args.Destination != `` {
+		if err := self.writeTable(result, args); err != nil {
+			return nil, err
+		}
+	}
+
+	return result.Maps(), nil
+}
+
+func (self *Commands) readTable(selector dom.Selector, args *TableArgs) (*browser.Table, error) {
+	if elements, err := self.browser.Tab().ElementQuery(selector, nil); err == nil {
+		if len(elements) == 0 {
+			return nil, fmt.Errorf("No tables matched %q", selector)
+		}
+
+		return self.browser.Tab().ReadTable(elements[0], args.HeaderRows)
+	} else {
+		return nil, err
+	}
+}
+
+// click the next page link, returning false if it is absent or disabled (i.e.: we're on the last page)
+func (self *Commands) clickNextPage(next dom.Selector) (bool, error) {
+	if link, err := self.nextPageLink(next); err == nil {
+		if link == nil {
+			return false, nil
+		}
+
+		if _, err := self.browser.Tab().EvaluateOn(link, `this.click()`); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	} else {
+		return false, err
+	}
+}
+
+// return the next page link, or nil if it is absent or disabled
+func (self *Commands) nextPageLink(next dom.Selector) (*dom.Element, error) {
+	if elements, err := self.browser.Tab().ElementQuery(next, nil); err == nil {
+		if len(elements) == 0 {
+			return nil, nil
+		}
+
+		link := elements[0]
+
+		if !link.Visible || !link.Enabled || typeutil.String(link.Attributes[`aria-disabled`]) == `true` {
+			return nil, nil
+		}
+
+		return link, nil
+	} else {
+		return nil, err
+	}
+}
+
+// wait for the table body to differ from the previous page.  If it never changes, returns nil if
+// the next page link has since gone away (i.e.: that was the last page), or an error if it's still
+// there, since the page we asked for never loaded.
+func (self *Commands) waitForNextPage(selector dom.Selector, previous *browser.Table, args *TableArgs) (*browser.Table, error) {
+	before, _ := json.Marshal(previous.Rows)
+	started := time.Now()
+
+	for time.Since(started) <= args.Timeout {
+		time.Sleep(args.Interval)
+
+		// the table may have been replaced, or may be mid-update, so errors here aren't fatal
+		if table, err := self.readTable(selector, args); err == nil {
+			if after, _ := json.Marshal(table.Rows); string(after) != string(before) {
+				return table, nil
+			}
+		}
+	}
+
+	if link, err := self.nextPageLink(args.Next); err != nil {
+		return nil, err
+	} else if link != nil {
+		return nil, fmt.Errorf("Timed out waiting for the next page of the table after %v", args.Timeout)
+	}
+
+	return nil, nil
+}
+
+func (self *Commands) writeTable(table *browser.Table, args *TableArgs) error {
+	var writer io.Writer
+	var format = strings.ToLower(args.Format)
+
+	if format == `` {
+		if strings.ToLower(filepath.Ext(args.Destination)) == `.csv` {
+			format = `csv`
+		} else {
+			format = `json`
+		}
+	}
+
+	if _, w, err := self.browser.GetWriterForPath(args.Destination); err == nil && w != nil {
+		writer = w
+	} else if file, err := os.Create(args.Destination); err == nil {
+		writer = file
+	} else {
+		return err
+	}
+
+	if closer, ok := writer.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	switch format {
+	case `csv`:
+		out := csv.NewWriter(writer)
+
+		if err := out.Write(table.Headers); err != nil {
+			return err
+		}
+
+		for _, row := range table.Rows {
+			record := make([]string, len(table.Headers))
+			copy(record, row)
+
+			if err := out.Write(record); err != nil {
+				return err
+			}
+		}
+
+		out.Flush()
+		return out.Error()
+
+	case `json`:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent(``, `  `)
+
+		// rows are written as objects whose keys are in column order, rather than as maps
+		rows := make([]tableRow, len(table.Rows))
+
+		for i, row := range table.Rows {
+			rows[i] = make(tableRow, len(table.Headers))
+
+			for j, header := range table.Headers {
+				rows[i][j].Header = header
+
+				if j < len(row) {
+					rows[i][j].Value = row[j]
+				}
+			}
+		}
+
+		return encoder.Encode(rows)
+
+	default:
+		return fmt.Errorf("Unsupported table format %q", format)
+	}
+}
+
+// A row of a table as header/value pairs, which is encoded as a JSON object that keeps the
+// table's column order.
+type tableRow []struct {
+	Header string
+	Value  string
+}
+
+func (self tableRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString(`{`)
+
+	for i, cell := range self {
+		if i > 0 {
+			buf.WriteString(`,`)
+		}
+
+		if key, err := json.Marshal(cell.Header); err == nil {
+			buf.Write(key)
+		} else {
+			return nil, err
+		}
+
+		buf.WriteString(`:`)
+
+		if value, err := json.Marshal(cell.Value); err == nil {
+			buf.Write(value)
+		} else {
+			return nil, err
+		}
+	}
+
+	buf.WriteString(`}`)
+
+	return buf.Bytes(), nil
+}
@@ -15,4 +15,22 @@ type Element struct {
 	Namespace  string                 `json:"namespace,omitempty"`
 	Attributes map[string]interface{} `json:"attributes"`
 	Text       string                 `json:"text,omitempty"`
+
+	// The element's bounding box (relative to the viewport) at the time it was selected.
+	Box *Dimensions `json:"box,omitempty"`
+
+	// Whether the element was rendered with a non-zero size and was not hidden by CSS.
+	Visible bool `json:"visible"`
+
+	// Whether the element was enabled (i.e.: not disabled, either directly or by a parent <fieldset>).
+	Enabled bool `json:"enabled"`
+
+	// The current value of form elements.
+	Value interface{} `json:"value,omitempty"`
+
+	// A CSS selector path uniquely describing the element's location in the document.
+	Path string `json:"path,omitempty"`
+
+	// Computed styles that were specifically requested when the element was selected.
+	Styles map[string]string `json:"styles,omitempty"`
 }
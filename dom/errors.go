@@ -15,6 +15,24 @@ func IsElementNotFoundErr(err error) bool {
 	return false
 }
 
+// Returns whether the given error indicates that an element no longer exists in the page.
+func IsStaleElementErr(err error) bool {
+	if err != nil {
+		if strings.Contains(err.Error(), `element is stale`) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func StaleElementErr(id string) error {
+	return fmt.Errorf(
+		"element is stale: %v no longer exists in the page. It may have been removed, or the page may have navigated since it was selected.",
+		id,
+	)
+}
+
 func TooManyMatchesErr(selector Selector, want int, have int) error {
 	return fmt.Errorf(
 		"Too many elements matched %q; expected %d, got %d. Please provide a more specific selector.",
@@ -23,3 +41,24 @@ func TooManyMatchesErr(selector Selector, want int, have int) error {
 		have,
 	)
 }
+
+// Returns whether the given error indicates that an element could not be interacted with.
+func IsNotActionableErr(err error) bool {
+	if err != nil {
+		if strings.Contains(err.Error(), `element is not actionable`) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NotActionableErr(element *Element, reason string) error {
+	var name = element.Path
+
+	if name == `` {
+		name = element.Name
+	}
+
+	return fmt.Errorf("element is not actionable: %v %s", name, reason)
+}
@@ -2,13 +2,27 @@ package dom
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/ghetzel/go-stockutil/stringutil"
 )
 
+// A Selector identifies elements on the page.  Plain selectors are CSS selectors; annotated
+// selectors take the form @type[expression], where type is one of "css", "xpath", "shadow" (a
+// CSS selector that also searches inside open and closed shadow roots), "role" (an ARIA role,
+// optionally followed by an accessible name, e.g.: @role[button "Sign in"]), or empty (to match
+// elements by their text, e.g.: @[Sign in]).
 type Selector string
 
+type TextMatchMode string
+
+const (
+	TextContains TextMatchMode = `contains`
+	TextExact                  = `exact`
+	TextRegex                  = `regex`
+)
+
 func (self *Selector) String() string {
 	return string(*self)
 }
@@ -37,7 +51,7 @@ func (self Selector) GetAnnotation() (string, string, error) {
 	switch atype {
 	case ``:
 		atype = `text`
-	case `xpath`, `css`:
+	case `xpath`, `css`, `shadow`, `role`:
 		break
 	default:
 		return ``, ``, fmt.Errorf("Unsupported annotation type %q", atype)
@@ -45,3 +59,59 @@ func (self Selector) GetAnnotation() (string, string, error) {
 
 	return atype, inner, nil
 }
+
+// Parse the inner expression of a text annotation into the mode that should be used to
+// match element text and the value to match against.  Text surrounded by double or single
+// quotes must match exactly (e.g.: @["Sign in"]), text surrounded by slashes is treated as a
+// regular expression with optional trailing flags (e.g.: @[/sign\s+in/i]), and anything else
+// must appear somewhere in the element's text (e.g.: @[Sign in]).
+func ParseTextMatch(inner string) (TextMatchMode, string, string) {
+	if len(inner) >= 2 {
+		if stringutil.IsSurroundedBy(inner, `"`, `"`) || stringutil.IsSurroundedBy(inner, `'`, `'`) {
+			return TextExact, inner[1 : len(inner)-1], ``
+		} else if strings.HasPrefix(inner, `/`) {
+			if i := strings.LastIndex(inner, `/`); i > 0 {
+				if flags := inner[i+1:]; strings.Trim(flags, `gimsuy`) == `` {
+					return TextRegex, inner[1:i], strings.Replace(flags, `g`, ``, -1)
+				}
+			}
+		}
+	}
+
+	return TextContains, inner, ``
+}
+
+// Parse the inner expression of a role annotation into the ARIA role and the (optional) accessible
+// name expression that follows it.  The name is matched the same way as a text annotation.
+func ParseRoleMatch(inner string) (string, string) {
+	inner = strings.TrimSpace(inner)
+
+	if i := strings.IndexAny(inner, " \t"); i > 0 {
+		return inner[:i], strings.TrimSpace(inner[i+1:])
+	} else {
+		return inner, ``
+	}
+}
+
+// Return whether the given text matches a text expression (as parsed by ParseTextMatch).
+func MatchText(expr string, text string) (bool, error) {
+	mode, value, flags := ParseTextMatch(expr)
+	text = strings.Join(strings.Fields(text), ` `)
+
+	switch mode {
+	case TextExact:
+		return (text == value), nil
+	case TextRegex:
+		if strings.Contains(flags, `i`) {
+			value = `(?i)` + value
+		}
+
+		if rx, err := regexp.Compile(value); err == nil {
+			return rx.MatchString(text), nil
+		} else {
+			return false, err
+		}
+	default:
+		return strings.Contains(text, value), nil
+	}
+}
@@ -340,15 +340,15 @@ func (self *Server) setupRoutes(router *vestigo.Router) {
 
 		if self.env.Browser() != nil {
 			if tab := self.env.Browser().Tab(); tab != nil {
-				if !tab.IsScreencasting() {
-					if err := tab.StartScreencast(
-						int(httputil.QInt(req, `q`, 65)),
-						int(httputil.QInt(req, `w`, 0)),
-						int(httputil.QInt(req, `h`, 0)),
-					); err != nil {
-						httputil.RespondJSON(w, reqerr, http.StatusConflict)
-						return
-					}
+				// this is a no-op if the UI is already screencasting; if only a recording is, the
+				// screencast is shared with it
+				if err := tab.StartScreencast(
+					int(httputil.QInt(req, `q`, 65)),
+					int(httputil.QInt(req, `w`, 0)),
+					int(httputil.QInt(req, `h`, 0)),
+				); err != nil {
+					httputil.RespondJSON(w, reqerr, http.StatusConflict)
+					return
 				}
 
 				if sid := req.Header.Get(`Sec-Websocket-Protocol`); sid != `` {